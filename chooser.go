@@ -0,0 +1,110 @@
+package tracery
+
+import "fmt"
+
+// Choice is a choice between the options of a symbol, for a Chooser to make
+type Choice struct {
+	// Symbol is the symbol being expanded, or being pushed to by an action,
+	// and is empty for options with no symbol
+	Symbol string
+	// Path is the derivation path of the node being expanded, e.g.
+	// `origin/0/animal` for the first animal in the first option of origin, as
+	// in a Trace. It's empty when Symbol is
+	Path string
+	// Options is the source of each option
+	Options []string
+}
+
+// Chooser picks which option to use, giving its index. Set Grammar.Chooser to
+// drive exact expansions, e.g. in tests or when debugging a grammar
+type Chooser interface {
+	Choose(c Choice) int
+}
+
+// ChooserFunc allows a function to be used directly as a Chooser
+type ChooserFunc func(c Choice) int
+
+func (f ChooserFunc) Choose(c Choice) int {
+	return f(c)
+}
+
+// RandomChooser picks options uniformly at random, as a grammar does without a
+// Chooser
+type RandomChooser struct {
+	Rand func(n int) int
+}
+
+func (r RandomChooser) Choose(c Choice) int {
+	return r.Rand(len(c.Options))
+}
+
+// FirstChooser always picks the first option
+type FirstChooser struct{}
+
+func (FirstChooser) Choose(c Choice) int {
+	return 0
+}
+
+// LastChooser always picks the last option
+type LastChooser struct{}
+
+func (LastChooser) Choose(c Choice) int {
+	return len(c.Options) - 1
+}
+
+// ScriptChooser picks the options given by its script, in order, then the
+// first option once the script runs out. Indexes past the last option are
+// wrapped around. A Strict chooser panics instead, so a script which no longer
+// matches its grammar is caught. Negative indexes always panic
+type ScriptChooser struct {
+	Script []int
+	Strict bool
+	next   int
+}
+
+func (s *ScriptChooser) Choose(c Choice) int {
+	if s.next >= len(s.Script) {
+		if s.Strict {
+			panic(fmt.Sprintf("tracery: script of %d choices ran out choosing %q", len(s.Script), c.Symbol))
+		}
+		return 0
+	}
+	i := s.Script[s.next]
+	s.next++
+	if i < 0 {
+		panic(fmt.Sprintf("tracery: script choice %d is negative (%d) choosing %q", s.next-1, i, c.Symbol))
+	}
+	if i >= len(c.Options) && s.Strict {
+		panic(fmt.Sprintf("tracery: script choice %d picks option %d of %d choosing %q", s.next-1, i, len(c.Options), c.Symbol))
+	}
+	return i % len(c.Options)
+}
+
+// MapChooser picks options by derivation path or symbol, e.g.
+// `{"animal": "fox"}` always picks the option `fox` of animal, while
+// `{"origin/0/animal[1]": "fox"}` picks it only for the second animal in the
+// first option of origin. A path takes precedence over its symbol. Choices not
+// in the map, or without the option, are left to the Fallback, or the first
+// option if there's no Fallback
+type MapChooser struct {
+	Picks    map[string]string
+	Fallback Chooser
+}
+
+func (m MapChooser) Choose(c Choice) int {
+	for _, key := range []string{c.Path, c.Symbol} {
+		pick, ok := m.Picks[key]
+		if !ok || key == "" {
+			continue
+		}
+		for i, option := range c.Options {
+			if option == pick {
+				return i
+			}
+		}
+	}
+	if m.Fallback == nil {
+		return 0
+	}
+	return m.Fallback.Choose(c)
+}
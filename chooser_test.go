@@ -0,0 +1,89 @@
+package tracery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChooser(t *testing.T) {
+	chooserGrammar := func(c Chooser) Grammar {
+		g := NewGrammar()
+		g.Chooser = c
+		g.PushRule("size", "big", "small", "tiny")
+		g.PushRule("animal", "fox", "dog", "#size# emu")
+		return g
+	}
+
+	var tests = []struct {
+		name     string
+		chooser  Chooser
+		expected string
+	}{
+		{"first", FirstChooser{}, "fox big"},
+		{"last", LastChooser{}, "tiny emu tiny"},
+		{"script", &ScriptChooser{Script: []int{2, 1, 4}}, "small emu small"},
+		{"script which runs out", &ScriptChooser{Script: []int{2}}, "big emu big"},
+		{"map", MapChooser{Picks: map[string]string{"animal": "#size# emu", "size": "small"}}, "small emu small"},
+		{"map with fallback", MapChooser{Picks: map[string]string{"size": "small"}, Fallback: LastChooser{}}, "small emu small"},
+		{"map by path", MapChooser{Picks: map[string]string{"animal": "#size# emu", "animal/2/size": "tiny", "size": "small"}}, "tiny emu small"},
+		{"map without the option", MapChooser{Picks: map[string]string{"size": "huge"}}, "fox big"},
+		{"random", RandomChooser{Rand: func(n int) int { return 1 }}, "dog small"},
+	}
+
+	for _, tt := range tests {
+		g := chooserGrammar(tt.chooser)
+		got := g.Flatten("#animal# #size#")
+		if got != tt.expected {
+			t.Errorf("%s: got '%s' want '%s'", tt.name, got, tt.expected)
+		}
+	}
+
+	t.Run("it is given the symbol and its options", func(t *testing.T) {
+		var got []Choice
+		g := chooserGrammar(ChooserFunc(func(c Choice) int {
+			got = append(got, c)
+			return 0
+		}))
+		g.Flatten("#animal#[x:a,#size#]")
+		want := []Choice{
+			{"animal", "animal", []string{"fox", "dog", "#size# emu"}},
+			{"x", "x", []string{"a", "#size#"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+	})
+	t.Run("it is given the path", func(t *testing.T) {
+		var got []string
+		g := chooserGrammar(ChooserFunc(func(c Choice) int {
+			got = append(got, c.Path)
+			return len(c.Options) - 1
+		}))
+		g.Flatten("#animal# #animal#")
+		want := []string{"animal", "animal/2/size", "animal[1]", "animal[1]/2/size"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+	})
+	t.Run("a strict script panics when it doesn't fit", func(t *testing.T) {
+		var scripts = []struct {
+			name   string
+			script []int
+		}{
+			{"runs out", []int{2}},
+			{"past the end", []int{3, 0}},
+			{"negative", []int{-1, 0}},
+		}
+		for _, tt := range scripts {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%s: expected a panic", tt.name)
+					}
+				}()
+				g := chooserGrammar(&ScriptChooser{Script: tt.script, Strict: true})
+				g.Flatten("#animal#")
+			}()
+		}
+	})
+}
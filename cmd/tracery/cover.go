@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/martletandco/tracery-go"
+	"github.com/martletandco/tracery-go/exec"
+)
+
+// runCover flattens a grammar many times and reports which options were never
+// chosen, giving the exit status
+func runCover(args []string) int {
+	flags := flag.NewFlagSet("cover", flag.ExitOnError)
+	nFlag := flags.Int("n", 10000, "number of times to flatten the expression")
+	exprFlag := flags.String("expr", "#origin#", "expression to flatten")
+	htmlFlag := flags.String("html", "", "also write the report as HTML to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tracery cover [flags] grammar files or directories...")
+		fmt.Fprintln(os.Stderr, "Reports how often each option of each symbol is chosen")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	g, err := tracery.LoadFiles(flags.Args()...)
+	if err != nil {
+		bail(err)
+	}
+
+	g.Coverage = exec.NewCoverage()
+	for i := 0; i < *nFlag; i++ {
+		g.Flatten(*exprFlag)
+	}
+
+	report := g.CoverageReport()
+	if err := report.WriteText(os.Stdout); err != nil {
+		bail(err)
+	}
+	if *htmlFlag != "" {
+		f, err := os.Create(*htmlFlag)
+		if err != nil {
+			bail(err)
+		}
+		if err := report.WriteHTML(f); err != nil {
+			bail(err)
+		}
+		if err := f.Close(); err != nil {
+			bail(err)
+		}
+	}
+	return 0
+}
@@ -1,32 +1,218 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/martletandco/tracery-go"
 )
 
 // Flags to add
 // Flatten expression (default #origin#)
-// Random seed
 // Rules to add (`symbol:value` format)
 
+var mergeFlag = flag.String("merge", "error", "how to merge symbols defined in more than one file: error, override or append")
+var formatFlag = flag.String("format", "json", "format of a grammar read from stdin: json, yaml or toml (files use their extension)")
+var historyFlag = flag.String("history", "", "file of past outputs to avoid repeating, which the output is added to")
+var windowFlag = flag.String("window", "", "how long past outputs are avoided for, e.g. 30d or 12h (default forever)")
+var similarityFlag = flag.Float64("similarity", 0, "also avoid outputs at least this similar to past ones, from 0.5 (unrelated) to 1 (same words)")
+var attemptsFlag = flag.Int("attempts", 100, "most outputs tried when avoiding past outputs")
+var rngFlag = flag.String("rng", "math", "random number generator: math (math/rand), pcg (stable across releases) or crypto (unpredictable)")
+var seedFlag = flag.String("seed", "", "seed for the math or pcg generator (default the time)")
+var pinFlag pins
+var svgDirFlag = flag.String("svg-dir", "", "take CBDQ {svg ...} and {img ...} blocks out of the output, writing each SVG to a file in this directory")
+
 func main() {
-	g := tracery.NewGrammar()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "test":
+			os.Exit(runTest(os.Args[2:]))
+		case "cover":
+			os.Exit(runCover(os.Args[2:]))
+		}
+	}
 
-	readInRuleSet(g)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tracery [flags] [grammar files or directories...]")
+		fmt.Fprintln(os.Stderr, "       tracery test [flags] grammar files or directories...")
+		fmt.Fprintln(os.Stderr, "       tracery cover [flags] grammar files or directories...")
+		fmt.Fprintln(os.Stderr, "Reads a grammar from stdin when no files are given")
+		flag.PrintDefaults()
+	}
+	flag.Var(&pinFlag, "pin", "always pick an option of a symbol, by its rule or index, e.g. animal=fox (repeatable)")
+	flag.Parse()
 
-	r := g.Flatten("#origin#")
+	g, err := loadGrammar(flag.Args())
+	if err != nil {
+		bail(err)
+	}
+	if err := setRNG(&g); err != nil {
+		bail(err)
+	}
+	for _, pin := range pinFlag {
+		if err := g.Pin(pin[0], pin[1]); err != nil {
+			bail(err)
+		}
+	}
+
+	var r string
+	if *historyFlag != "" {
+		r, err = flattenNew(&g, *historyFlag)
+		if err != nil {
+			bail(err)
+		}
+	} else {
+		r = g.Flatten("#origin#")
+	}
+	if *svgDirFlag != "" {
+		r, err = writeMedia(r, *svgDirFlag)
+		if err != nil {
+			bail(err)
+		}
+	}
 
 	os.Stdout.WriteString(r)
 	os.Stdout.WriteString("\n")
 	os.Exit(0)
 }
 
-func readInRuleSet(g tracery.Grammar) {
+func loadGrammar(paths []string) (tracery.Grammar, error) {
+	if len(paths) == 0 {
+		g := tracery.NewGrammar()
+		readInRuleSet(&g)
+		return g, nil
+	}
+
+	var l tracery.Loader
+	switch *mergeFlag {
+	case "error":
+		l.Policy = tracery.MergeError
+	case "override":
+		l.Policy = tracery.MergeOverride
+	case "append":
+		l.Policy = tracery.MergeAppend
+	default:
+		return tracery.Grammar{}, fmt.Errorf("unknown merge policy %q", *mergeFlag)
+	}
+
+	return l.LoadFiles(paths...)
+}
+
+// setRNG gives the grammar the generator picked by the -rng and -seed flags
+func setRNG(g *tracery.Grammar) error {
+	seed := time.Now().UnixNano()
+	if *seedFlag != "" {
+		var err error
+		seed, err = strconv.ParseInt(*seedFlag, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid seed %q", *seedFlag)
+		}
+	}
+
+	switch *rngFlag {
+	case "math":
+		g.Rand = rand.New(rand.NewSource(seed)).Intn
+	case "pcg":
+		g.SetRNG(tracery.NewPCG(uint64(seed), 0))
+	case "crypto":
+		if *seedFlag != "" {
+			return fmt.Errorf("the crypto generator can't be seeded")
+		}
+		g.SetRNG(tracery.CryptoRNG{})
+	default:
+		return fmt.Errorf("unknown generator %q", *rngFlag)
+	}
+	return nil
+}
+
+// pins is a list of symbol=option flags
+type pins [][2]string
+
+func (p *pins) String() string {
+	var out []string
+	for _, pin := range *p {
+		out = append(out, pin[0]+"="+pin[1])
+	}
+	return strings.Join(out, ",")
+}
+
+func (p *pins) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i < 0 {
+		return fmt.Errorf("expected symbol=option, got %q", value)
+	}
+	*p = append(*p, [2]string{value[:i], value[i+1:]})
+	return nil
+}
+
+// flattenNew flattens an output not seen in the history, and adds it
+func flattenNew(g *tracery.Grammar, path string) (string, error) {
+	h, err := tracery.OpenHistory(path)
+	if err != nil {
+		return "", err
+	}
+	h.Window, err = parseWindow(*windowFlag)
+	if err != nil {
+		return "", err
+	}
+	h.Similarity = *similarityFlag
+
+	r, err := g.FlattenMeeting("#origin#", *attemptsFlag, h.Unseen())
+	if err != nil {
+		return "", err
+	}
+	return r, h.Add(r)
+}
+
+// parseWindow reads a duration, which can also be in days, e.g. 30d
+func parseWindow(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// writeMedia writes each SVG in the output to dir, listing the files written
+// and any image URLs on stderr. It returns the text left once they're removed
+func writeMedia(out string, dir string) (string, error) {
+	r := tracery.ExtractMedia(out)
+	if len(r.Media) == 0 {
+		return r.Text, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	for i, media := range r.Media {
+		switch media.Kind {
+		case "svg":
+			path := filepath.Join(dir, fmt.Sprintf("media-%d.svg", i+1))
+			if err := ioutil.WriteFile(path, []byte(media.Body), 0644); err != nil {
+				return "", err
+			}
+			fmt.Fprintln(os.Stderr, "svg:", path)
+		case "img":
+			fmt.Fprintln(os.Stderr, "img:", media.Body)
+		}
+	}
+	return r.Text, nil
+}
+
+func readInRuleSet(g *tracery.Grammar) {
 	fi, err := os.Stdin.Stat()
 	if err != nil {
 		panic(err)
@@ -44,13 +230,13 @@ func readInRuleSet(g tracery.Grammar) {
 		return
 	}
 
-	var set tracery.RuleSet
-	if err := json.Unmarshal(buf, &set); err != nil {
+	set, err := tracery.ParseRuleSet(*formatFlag, buf)
+	if err != nil {
 		// @cleanup: Add better error
 		bail(err)
 	}
 
-	g.PushRuleSet(set)
+	g.PushOrderedRuleSet(set)
 }
 
 func bail(err error) {
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/martletandco/tracery-go"
+	"github.com/martletandco/tracery-go/tracerytest"
+)
+
+// runTest runs golden tests against a grammar, giving the exit status
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	casesFlag := flags.String("cases", "", "JSON file of test cases (default the \"$tests\" section of each JSON grammar file, including those in directories)")
+	updateFlag := flags.Bool("update", false, "rewrite the expected output of every case to the actual output")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tracery test [flags] grammar files or directories...")
+		fmt.Fprintln(os.Stderr, "Runs {expr, seed, expected} cases against the grammar")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	g, err := tracery.LoadFiles(flags.Args()...)
+	if err != nil {
+		bail(err)
+	}
+
+	files := []string{*casesFlag}
+	if *casesFlag == "" {
+		files, err = jsonFiles(flags.Args())
+		if err != nil {
+			bail(err)
+		}
+	}
+
+	var all []tracerytest.Result
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			bail(err)
+		}
+		cases, err := tracerytest.ParseCases(data)
+		if err != nil {
+			bail(fmt.Errorf("%s: %v", path, err))
+		}
+
+		results := tracerytest.Run(&g, cases)
+		all = append(all, results...)
+
+		if *updateFlag {
+			out, err := tracerytest.Update(data, results)
+			if err != nil {
+				bail(fmt.Errorf("%s: %v", path, err))
+			}
+			if err := os.WriteFile(path, out, 0644); err != nil {
+				bail(err)
+			}
+			continue
+		}
+		for _, r := range results {
+			if !r.Passed() {
+				fmt.Printf("%s: %s", path, r.Diff())
+			}
+		}
+	}
+
+	if *updateFlag {
+		if len(all) == 0 {
+			fmt.Fprintln(os.Stderr, "tracery test: no cases found")
+			return 1
+		}
+		fmt.Printf("updated %d cases\n", len(all))
+		return 0
+	}
+	if len(all) == 0 {
+		fmt.Fprintln(os.Stderr, "tracery test: no cases found")
+		return 1
+	}
+	fmt.Println(tracerytest.Summary(all))
+	for _, r := range all {
+		if !r.Passed() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// jsonFiles lists the JSON grammar files among paths, looking in directories
+// the same way LoadFiles does. Only JSON grammars can carry a tests section
+func jsonFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if format, _ := tracery.FormatOf(p); format == "json" {
+				files = append(files, p)
+			}
+			continue
+		}
+
+		names, err := fs.Glob(os.DirFS(p), "*")
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			path := filepath.Join(p, name)
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if format, _ := tracery.FormatOf(name); format == "json" && !info.IsDir() {
+				files = append(files, path)
+			}
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,136 @@
+package tracery
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/martletandco/tracery-go/parse"
+)
+
+// Output is a flattened output, as checked by a Constraint
+type Output struct {
+	Text string
+	// symbols expanded to make the text
+	symbols map[string]bool
+}
+
+// Includes reports whether the symbol was expanded as part of the output
+func (o Output) Includes(key string) bool {
+	return o.symbols[key]
+}
+
+// Constraint is a check an output must pass to be returned by FlattenMeeting.
+// Reason describes what the constraint wants, and is reported when no output
+// passes it
+type Constraint struct {
+	Reason string
+	Check  func(out Output) bool
+}
+
+// MaxLength wants outputs of at most n runes
+func MaxLength(n int) Constraint {
+	return Constraint{
+		Reason: fmt.Sprintf("at most %d characters", n),
+		Check:  func(out Output) bool { return utf8.RuneCountInString(out.Text) <= n },
+	}
+}
+
+// MinLength wants outputs of at least n runes
+func MinLength(n int) Constraint {
+	return Constraint{
+		Reason: fmt.Sprintf("at least %d characters", n),
+		Check:  func(out Output) bool { return utf8.RuneCountInString(out.Text) >= n },
+	}
+}
+
+// Matches wants outputs matching the regular expression
+func Matches(re *regexp.Regexp) Constraint {
+	return Constraint{
+		Reason: fmt.Sprintf("matches /%s/", re),
+		Check:  func(out Output) bool { return re.MatchString(out.Text) },
+	}
+}
+
+// NotMatches wants outputs which don't match the regular expression, e.g. to
+// keep out banned phrases
+func NotMatches(re *regexp.Regexp) Constraint {
+	return Constraint{
+		Reason: fmt.Sprintf("does not match /%s/", re),
+		Check:  func(out Output) bool { return !re.MatchString(out.Text) },
+	}
+}
+
+// IncludesSymbol wants outputs which expanded the symbol somewhere along the way
+func IncludesSymbol(key string) Constraint {
+	return Constraint{
+		Reason: fmt.Sprintf("includes #%s#", key),
+		Check:  func(out Output) bool { return out.Includes(key) },
+	}
+}
+
+// ConstraintError is returned when no output met the constraints
+type ConstraintError struct {
+	Attempts int
+	// Failures counts the outputs failing each constraint, by its reason. An
+	// output failing more than one constraint is counted against each
+	Failures map[string]int
+}
+
+func (e *ConstraintError) Error() string {
+	reasons := make([]string, 0, len(e.Failures))
+	for reason := range e.Failures {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	var failures []string
+	for _, reason := range reasons {
+		failures = append(failures, fmt.Sprintf("%s (failed %d)", reason, e.Failures[reason]))
+	}
+	return fmt.Sprintf("no output met the constraints in %d attempts: %s", e.Attempts, strings.Join(failures, ", "))
+}
+
+// FlattenWhere flattens the input until the constraint accepts the output,
+// giving up after maxAttempts. Only the accepted attempt's actions are kept
+// by a Persistent grammar
+func (g *Grammar) FlattenWhere(input string, constraint func(string) bool, maxAttempts int) (string, error) {
+	return g.FlattenMeeting(input, maxAttempts, Constraint{
+		Reason: "accepted by the constraint",
+		Check:  func(out Output) bool { return constraint(out.Text) },
+	})
+}
+
+// FlattenMeeting flattens the input until an output passes every constraint,
+// giving up after maxAttempts. The error is a *ConstraintError saying which
+// constraints were failed and how often
+func (g *Grammar) FlattenMeeting(input string, maxAttempts int, constraints ...Constraint) (string, error) {
+	tree := parse.String(input)
+	failures := make(map[string]int)
+
+	for i := 0; i < maxAttempts; i++ {
+		text, s := g.flatten(tree)
+		if meets(Output{Text: text, symbols: s.symbols}, constraints, failures) {
+			if g.Persistent {
+				s.commit()
+			}
+			return text, nil
+		}
+	}
+
+	return "", &ConstraintError{Attempts: maxAttempts, Failures: failures}
+}
+
+// meets checks the output against every constraint, counting the failures
+func meets(out Output, constraints []Constraint, failures map[string]int) bool {
+	ok := true
+	for _, c := range constraints {
+		if !c.Check(out) {
+			failures[c.Reason]++
+			ok = false
+		}
+	}
+	return ok
+}
@@ -0,0 +1,82 @@
+package tracery
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestFlattenWhere(t *testing.T) {
+	t.Run("it retries until the constraint accepts the output", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("animal", "fox", "dog", "emu")
+		i := 0
+		g.Rand = func(n int) int { i++; return i % n }
+		got, err := g.FlattenWhere("#animal#", func(out string) bool { return out == "fox" }, 5)
+		want := "fox"
+		if err != nil || got != want {
+			t.Errorf("got '%s' (%v) want '%s'", got, err, want)
+		}
+	})
+	t.Run("it reports the attempts when nothing is accepted", func(t *testing.T) {
+		g := NewGrammar()
+		_, err := g.FlattenWhere("a", func(out string) bool { return false }, 3)
+		var cerr *ConstraintError
+		if !errors.As(err, &cerr) || cerr.Attempts != 3 {
+			t.Errorf("got '%v' want 3 attempts", err)
+		}
+	})
+	t.Run("it only keeps the actions of the accepted output", func(t *testing.T) {
+		g := NewGrammar()
+		g.Persistent = true
+		g.PushRule("animal", "fox", "dog")
+		i := 0
+		g.Rand = func(n int) int { i++; return i % n }
+		got, _ := g.FlattenWhere("[pet:#animal#]#pet#", func(out string) bool { return out == "fox" }, 5)
+		if got != "fox" || g.StackDepth("pet") != 1 {
+			t.Errorf("got '%s' with %d pushes want 'fox' with 1", got, g.StackDepth("pet"))
+		}
+	})
+}
+
+func TestFlattenMeeting(t *testing.T) {
+	var tests = []struct {
+		constraint Constraint
+		input      string
+		pass       bool
+	}{
+		{MaxLength(3), "🦊🦊🦊", true},
+		{MaxLength(3), "fox!", false},
+		{MinLength(3), "🦊🦊🦊", true},
+		{MinLength(3), "ox", false},
+		{Matches(regexp.MustCompile(`^f`)), "fox", true},
+		{Matches(regexp.MustCompile(`^f`)), "ox", false},
+		{NotMatches(regexp.MustCompile(`(?i)dog`)), "fox", true},
+		{NotMatches(regexp.MustCompile(`(?i)dog`)), "hot Dog", false},
+		{IncludesSymbol("animal"), "#animal#", true},
+		{IncludesSymbol("animal"), "animal", false},
+		{IncludesSymbol("animal"), "#nope#", false},
+	}
+
+	for _, tt := range tests {
+		g := NewGrammar()
+		g.PushRule("animal", "fox")
+		_, err := g.FlattenMeeting(tt.input, 1, tt.constraint)
+		if pass := err == nil; pass != tt.pass {
+			t.Errorf("%s on '%s': got %v want %v", tt.constraint.Reason, tt.input, pass, tt.pass)
+		}
+	}
+
+	t.Run("it reports which constraints failed", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("animal", "fox", "hound dog")
+		i := 0
+		g.Rand = func(n int) int { i++; return i % n }
+		_, err := g.FlattenMeeting("#animal#", 4, MaxLength(5), MinLength(4))
+		got := err.Error()
+		want := "no output met the constraints in 4 attempts: at least 4 characters (failed 2), at most 5 characters (failed 2)"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package tracery
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+
+	"github.com/martletandco/tracery-go/exec"
+)
+
+// CoverageReport shows which options of each symbol were chosen, and which
+// modifiers were called, as counted by the grammar's Coverage
+type CoverageReport struct {
+	Symbols   []SymbolCoverage
+	Modifiers []ModifierCoverage
+}
+
+// SymbolCoverage is the number of times each option of a symbol was chosen
+type SymbolCoverage struct {
+	Symbol  string
+	Options []OptionCoverage
+}
+
+type OptionCoverage struct {
+	Rule string
+	Hits int
+}
+
+type ModifierCoverage struct {
+	Name  string
+	Calls int
+}
+
+// Covered is the number of options chosen at least once
+func (s SymbolCoverage) Covered() int {
+	covered := 0
+	for _, option := range s.Options {
+		if option.Hits > 0 {
+			covered++
+		}
+	}
+	return covered
+}
+
+// Percent is the share of options chosen at least once
+func (s SymbolCoverage) Percent() float64 {
+	if len(s.Options) == 0 {
+		return 100
+	}
+	return 100 * float64(s.Covered()) / float64(len(s.Options))
+}
+
+// Share is the percentage of the symbol's choices which went to option i
+func (s SymbolCoverage) Share(i int) float64 {
+	total := 0
+	for _, option := range s.Options {
+		total += option.Hits
+	}
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(s.Options[i].Hits) / float64(total)
+}
+
+// CoverageReport reports on the rules given to PushRule, PushRules and
+// PushRuleSet, in sorted order. It's empty if the grammar has no Coverage
+func (g *Grammar) CoverageReport() CoverageReport {
+	var r CoverageReport
+	if g.Coverage == nil {
+		return r
+	}
+
+	keys := make([]string, 0, len(g.base))
+	for key := range g.base {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := SymbolCoverage{Symbol: key}
+		op := g.base[key].top()
+		if sel, ok := op.(exec.Select); ok {
+			hits := g.Coverage.ChoiceHits(sel)
+			for i, option := range sel.Options() {
+				s.Options = append(s.Options, OptionCoverage{Rule: exec.Source(option), Hits: hits[i]})
+			}
+		} else {
+			s.Options = []OptionCoverage{{Rule: exec.Source(op), Hits: g.Coverage.SymbolHits(key)}}
+		}
+		r.Symbols = append(r.Symbols, s)
+	}
+
+	for _, name := range g.Modifiers() {
+		r.Modifiers = append(r.Modifiers, ModifierCoverage{Name: name, Calls: g.Coverage.ModifierHits(name)})
+	}
+	return r
+}
+
+// Percent is the share of every symbol's options chosen at least once
+func (r CoverageReport) Percent() float64 {
+	covered, total := 0, 0
+	for _, s := range r.Symbols {
+		covered += s.Covered()
+		total += len(s.Options)
+	}
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(covered) / float64(total)
+}
+
+// WriteText writes the report as plain text, listing each option never chosen
+func (r CoverageReport) WriteText(w io.Writer) error {
+	for _, s := range r.Symbols {
+		fmt.Fprintf(w, "%s: %.1f%% of %d options\n", s.Symbol, s.Percent(), len(s.Options))
+		for i, option := range s.Options {
+			if option.Hits == 0 {
+				fmt.Fprintf(w, "  never   %q\n", option.Rule)
+				continue
+			}
+			fmt.Fprintf(w, "  %5.1f%%  %q\n", s.Share(i), option.Rule)
+		}
+	}
+	for _, m := range r.Modifiers {
+		if m.Calls == 0 {
+			fmt.Fprintf(w, ".%s: never called\n", m.Name)
+			continue
+		}
+		fmt.Fprintf(w, ".%s: %d calls\n", m.Name, m.Calls)
+	}
+	_, err := fmt.Fprintf(w, "total: %.1f%% of options chosen\n", r.Percent())
+	return err
+}
+
+var coverageHTML = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Grammar coverage</title>
+<style>
+body { font-family: sans-serif; }
+td { padding: 0 1em 0 0; }
+.never { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Grammar coverage: {{printf "%.1f" .Percent}}%</h1>
+{{range .Symbols}}{{$s := .}}
+<h2>{{.Symbol}} <small>{{printf "%.1f" .Percent}}% of {{len .Options}} options</small></h2>
+<table>
+{{range $i, $o := .Options}}<tr{{if eq $o.Hits 0}} class="never"{{end}}><td>{{if eq $o.Hits 0}}never{{else}}{{printf "%.1f" ($s.Share $i)}}%{{end}}</td><td><code>{{$o.Rule}}</code></td></tr>
+{{end}}</table>
+{{end}}
+{{if .Modifiers}}<h2>Modifiers</h2>
+<table>
+{{range .Modifiers}}<tr{{if eq .Calls 0}} class="never"{{end}}><td>.{{.Name}}</td><td>{{if eq .Calls 0}}never called{{else}}{{.Calls}} calls{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a standalone HTML page
+func (r CoverageReport) WriteHTML(w io.Writer) error {
+	return coverageHTML.Execute(w, r)
+}
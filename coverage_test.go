@@ -0,0 +1,95 @@
+package tracery
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/martletandco/tracery-go/exec"
+)
+
+func TestCoverage(t *testing.T) {
+	cover := func() Grammar {
+		g := NewGrammar()
+		g.Coverage = exec.NewCoverage()
+		g.PushRule("origin", "#animal.upper#")
+		g.PushRule("animal", "fox", "dog", "emu")
+		g.AddModifyFunc("upper", func(value string, params ...string) string {
+			return strings.ToUpper(value)
+		})
+		g.AddModifyFunc("lower", func(value string, params ...string) string {
+			return strings.ToLower(value)
+		})
+		// Never picks emu
+		i := 0
+		g.Rand = func(n int) int { i++; return i % 2 }
+		for j := 0; j < 4; j++ {
+			g.Flatten("#origin#")
+		}
+		return g
+	}
+
+	t.Run("it counts each option chosen", func(t *testing.T) {
+		g := cover()
+		r := g.CoverageReport()
+		if len(r.Symbols) != 2 {
+			t.Fatalf("got '%v'", r.Symbols)
+		}
+		animal := r.Symbols[0]
+		var hits []int
+		for _, option := range animal.Options {
+			hits = append(hits, option.Hits)
+		}
+		if animal.Symbol != "animal" || len(hits) != 3 || hits[0] != 2 || hits[1] != 2 || hits[2] != 0 {
+			t.Errorf("got '%v'", animal)
+		}
+		if got := r.Symbols[1].Options[0].Hits; got != 4 {
+			t.Errorf("got %d hits of origin want 4", got)
+		}
+		if got, want := r.Percent(), 75.0; got != want {
+			t.Errorf("got %.1f want %.1f", got, want)
+		}
+	})
+	t.Run("it only counts the grammar's own rules", func(t *testing.T) {
+		g := cover()
+		g.Persistent = true
+		g.Flatten("[animal:cat,cow]#animal#")
+		pushed := g.value["animal"].top().(exec.Select)
+		if hits := g.Coverage.ChoiceHits(pushed); hits[0] != 0 || hits[1] != 0 {
+			t.Errorf("got '%v' want no hits of pushed rules", hits)
+		}
+		base := g.base["animal"].top().(exec.Select)
+		if hits := g.Coverage.ChoiceHits(base); hits[0]+hits[1]+hits[2] != 4 {
+			t.Errorf("got '%v' want 4 hits of the grammar's rules", hits)
+		}
+	})
+	t.Run("it writes a text report listing what was missed", func(t *testing.T) {
+		g := cover()
+		var buf bytes.Buffer
+		g.CoverageReport().WriteText(&buf)
+		got := buf.String()
+		want := `animal: 66.7% of 3 options
+   50.0%  "fox"
+   50.0%  "dog"
+  never   "emu"
+origin: 100.0% of 1 options
+  100.0%  "#animal.upper#"
+.lower: never called
+.upper: 4 calls
+total: 75.0% of options chosen
+`
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it writes an HTML report", func(t *testing.T) {
+		g := cover()
+		var buf bytes.Buffer
+		if err := g.CoverageReport().WriteHTML(&buf); err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		if got := buf.String(); !strings.Contains(got, `<tr class="never"><td>never</td><td><code>emu</code></td></tr>`) {
+			t.Errorf("got '%s'", got)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package tracery
+
+import (
+	"strings"
+
+	"github.com/martletandco/tracery-go/exec"
+)
+
+// syntax is every character the scanner can treat as grammar, wherever it is
+const syntax = `\#[](),:.{}`
+
+// Escape makes text safe to use as part of a rule, anywhere in a rule, e.g.
+// inside an action or a modifier param. The escaped text parses back to the
+// exact text given, however many `#`, `[` or `,` it has. POP and CLEAR have
+// their first letter escaped, so they're pushed as text rather than popping or
+// clearing when they're an action's whole value
+func Escape(s string) string {
+	if s == "POP" || s == "CLEAR" {
+		return `\` + s
+	}
+	if !strings.ContainsAny(s, syntax) {
+		return s
+	}
+	var out strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(syntax, r) {
+			out.WriteByte('\\')
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// PushLiteral pushes text to a symbol without parsing it, so it's always used
+// as is. Use this rather than PushRule for text from users. As with PushRule
+// more than one value will be selected between at random
+func (g *Grammar) PushLiteral(key string, values ...string) {
+	var ops []exec.Operation
+	for _, value := range values {
+		ops = append(ops, exec.NewLiteral(value))
+	}
+
+	switch len(ops) {
+	case 0:
+		g.pushBase(key, exec.NewLiteral(""))
+	case 1:
+		g.pushBase(key, ops[0])
+	default:
+		g.pushBase(key, exec.NewSelect(ops))
+	}
+}
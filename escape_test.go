@@ -0,0 +1,103 @@
+package tracery
+
+import (
+	"testing"
+
+	"github.com/martletandco/tracery-go/exec"
+	"github.com/martletandco/tracery-go/parse"
+)
+
+var untrusted = []string{
+	"",
+	"Ada",
+	"#hero#",
+	"[hero:Ada]",
+	"[hero:POP]",
+	"POP",
+	"CLEAR",
+	"Dr. No, (the) villain: #1",
+	`back\slash\`,
+	`\#`,
+	"#x.capitalize#",
+	"🥝 [a,b]",
+}
+
+func TestEscape(t *testing.T) {
+	t.Run("it parses back to the same text", func(t *testing.T) {
+		for _, s := range untrusted {
+			got := parse.String(Escape(s)).Resolve(nil)
+			if got != s {
+				t.Errorf("Escape(%v): got '%s' want '%s'", s, got, s)
+			}
+		}
+	})
+	t.Run("it is safe inside actions and params", func(t *testing.T) {
+		for _, s := range untrusted {
+			g := NewGrammar()
+			g.AddModifyFunc("first", func(value string, params ...string) string {
+				return params[0]
+			})
+			got := g.Flatten("[x:" + Escape(s) + "]#x.first(" + Escape(s) + ")#")
+			if got != s {
+				t.Errorf("Escape(%v): got '%s' want '%s'", s, got, s)
+			}
+		}
+	})
+	t.Run("it pushes POP and CLEAR as text", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("name", "Ada")
+		got := g.Flatten("[name:" + Escape("POP") + "]#name# [name:" + Escape("CLEAR") + "]#name#")
+		want := "POP CLEAR"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		op := parse.String("[name:" + Escape("POP") + "]")
+		if got, want := exec.Source(op), `[name:\POP]`; got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it leaves plain text alone", func(t *testing.T) {
+		got := Escape("hello world")
+		want := "hello world"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+}
+
+func TestPushLiteral(t *testing.T) {
+	t.Run("it pushes text without parsing it", func(t *testing.T) {
+		for _, s := range untrusted {
+			g := NewGrammar()
+			g.PushRule("hero", "Bo")
+			g.PushLiteral("name", s)
+			got := g.Flatten("#name#")
+			if got != s {
+				t.Errorf("PushLiteral(%v): got '%s' want '%s'", s, got, s)
+			}
+		}
+	})
+	t.Run("it selects one of many values", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushLiteral("name", "#a#", "#b#")
+		g.Rand = func(n int) int { return 1 }
+		got := g.Flatten("#name#")
+		want := "#b#"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it keeps literals through a reset", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushLiteral("name", "[x]")
+		g.Reset()
+		got := g.Flatten("#name#")
+		want := "[x]"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		if rules := g.Rules("name"); len(rules) != 1 || rules[0] != `\[x]` {
+			t.Errorf("got rules '%v'", rules)
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package exec
+
+import "fmt"
+
+// Block is a brace delimited block, e.g. CBDQ's `{svg ...}` or `{img ...}`.
+// Its body is expanded, but otherwise passed through as is with the braces
+type Block struct {
+	body Operation
+}
+
+func NewBlock(body Operation) Block {
+	return Block{body: body}
+}
+
+func (r Block) Resolve(ctx Context) string {
+	write(ctx, "{")
+	body := r.body.Resolve(ctx)
+	write(ctx, "}")
+	return "{" + body + "}"
+}
+func (r Block) Source() string {
+	return "{" + source(r.body, blockBody) + "}"
+}
+func (r Block) String() string {
+	return fmt.Sprintf("Block<%v>", r.body)
+}
@@ -0,0 +1,26 @@
+package exec
+
+import "fmt"
+
+// Call is an action which expands a tag only for the actions it performs,
+// e.g. `[#setPronouns#]`, so shared setup can live in its own symbol
+type Call struct {
+	tag Operation
+}
+
+func NewCall(tag Operation) Call {
+	return Call{tag: tag}
+}
+
+func (r Call) Resolve(ctx Context) string {
+	hold(ctx)
+	r.tag.Resolve(ctx)
+	release(ctx)
+	return ""
+}
+func (r Call) Source() string {
+	return "[" + Source(r.tag) + "]"
+}
+func (r Call) String() string {
+	return fmt.Sprintf("Call<%v>", r.tag)
+}
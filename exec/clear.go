@@ -0,0 +1,26 @@
+package exec
+
+import "fmt"
+
+type Clear struct {
+	key string
+}
+
+func NewClear(key string) Clear {
+	return Clear{key: key}
+}
+
+func (r Clear) Resolve(ctx Context) string {
+	if c, ok := ctx.(Clearer); ok {
+		c.Clear(r.key)
+	} else {
+		ctx.Pop(r.key)
+	}
+	return ""
+}
+func (r Clear) Source() string {
+	return "[" + escape(r.key, actionKey) + ":CLEAR]"
+}
+func (r Clear) String() string {
+	return fmt.Sprintf("Clear<%s>", r.key)
+}
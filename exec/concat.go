@@ -20,6 +20,9 @@ func (r Concat) Resolve(ctx Context) string {
 	}
 	return strings.Join(out, "")
 }
+func (r Concat) Source() string {
+	return source(r, topLevel)
+}
 func (r Concat) String() string {
 	return fmt.Sprintf("Concat<%d:%v>", len(r.rules), r.rules)
 }
@@ -0,0 +1,75 @@
+package exec
+
+import "sync"
+
+// Coverage counts how often each symbol is expanded, each option of a Select
+// is chosen, and each modifier is called, across any number of expansions. It
+// can be shared between goroutines
+type Coverage struct {
+	mu        sync.Mutex
+	symbols   map[string]int
+	choices   map[*Operation][]int
+	modifiers map[string]int
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{
+		symbols:   make(map[string]int),
+		choices:   make(map[*Operation][]int),
+		modifiers: make(map[string]int),
+	}
+}
+
+// Symbol counts an expansion of the symbol
+func (c *Coverage) Symbol(key string) {
+	c.mu.Lock()
+	c.symbols[key]++
+	c.mu.Unlock()
+}
+
+// Choice counts option i being chosen by the Select. Each Select counted is
+// kept, so only count those which last, like a grammar's rules
+func (c *Coverage) Choice(sel Select, i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Copies of a Select share their options, so the first identifies them
+	id := &sel.ops[0]
+	hits, ok := c.choices[id]
+	if !ok {
+		hits = make([]int, len(sel.ops))
+		c.choices[id] = hits
+	}
+	hits[i]++
+}
+
+// Modifier counts a call of the modifier
+func (c *Coverage) Modifier(key string) {
+	c.mu.Lock()
+	c.modifiers[key]++
+	c.mu.Unlock()
+}
+
+// SymbolHits is the number of times the symbol was expanded
+func (c *Coverage) SymbolHits(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.symbols[key]
+}
+
+// ChoiceHits gives the number of times each option of the Select was chosen
+func (c *Coverage) ChoiceHits(sel Select) []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hits := make([]int, len(sel.ops))
+	if len(sel.ops) > 0 {
+		copy(hits, c.choices[&sel.ops[0]])
+	}
+	return hits
+}
+
+// ModifierHits is the number of times the modifier was called
+func (c *Coverage) ModifierHits(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.modifiers[key]
+}
@@ -4,6 +4,12 @@ type Operation interface {
 	Resolve(ctx Context) string
 }
 
+// Sourcer is optionally implemented by an Operation which can give back the
+// rule text which parses to it, as every Operation in this package does
+type Sourcer interface {
+	Source() string
+}
+
 type Modifier interface {
 	Modify(value string, params ...string) string
 }
@@ -16,3 +22,33 @@ type Context interface {
 	Intn(n int) int
 	LookupModifier(key string) (Modifier, bool)
 }
+
+// Clearer is optionally implemented by a Context which can remove every rule
+// of a symbol. A Context without it has a Clear pop the symbol instead
+type Clearer interface {
+	Clear(key string)
+}
+
+// Chooser is optionally implemented by a Context which picks options itself,
+// rather than at random with Intn. The symbol is the one whose rules the
+// options are, or the one an action is pushing to, and is empty otherwise
+type Chooser interface {
+	Choose(symbol string, options []Operation) int
+}
+
+// Tracer is optionally implemented by a Context which keeps track of where it
+// is in the derivation. Enter is called before resolving the rules of a symbol,
+// or the value an action pushes to a symbol, and Leave once they're resolved
+type Tracer interface {
+	Enter(symbol string)
+	Leave()
+}
+
+// Observer is optionally implemented by a Context which wants to know about
+// each symbol as it's expanded, each option chosen and each modifier called.
+// The symbol of a choice is as given to a Chooser
+type Observer interface {
+	ObserveSymbol(key string)
+	ObserveChoice(symbol string, sel Select, i int)
+	ObserveModifier(key string)
+}
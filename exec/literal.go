@@ -10,9 +10,17 @@ func NewLiteral(value string) Literal {
 	return Literal{value: value}
 }
 
-func (r Literal) Resolve(ctx Context) string {
+// Value is the literal's text
+func (r Literal) Value() string {
 	return r.value
 }
+
+func (r Literal) Resolve(ctx Context) string {
+	return write(ctx, r.value)
+}
+func (r Literal) Source() string {
+	return source(r, topLevel)
+}
 func (r Literal) String() string {
 	return fmt.Sprintf("Literal<%v>", r.value)
 }
@@ -14,6 +14,9 @@ func (r Pop) Resolve(ctx Context) string {
 	ctx.Pop(r.key)
 	return ""
 }
+func (r Pop) Source() string {
+	return "[" + escape(r.key, actionKey) + ":POP]"
+}
 func (r Pop) String() string {
 	return fmt.Sprintf("Pop<%s>", r.key)
 }
@@ -12,10 +12,20 @@ func NewPush(key string, value Operation) Push {
 }
 
 func (r Push) Resolve(ctx Context) string {
-	result := r.value.Resolve(ctx)
+	hold(ctx)
+	result := resolveFor(r.value, ctx, r.key)
+	release(ctx)
 	ctx.Push(r.key, NewLiteral(result))
 	return ""
 }
+func (r Push) Source() string {
+	value := source(r.value, actionValue)
+	if value == "POP" || value == "CLEAR" {
+		// Text, not a Pop or Clear
+		value = `\` + value
+	}
+	return "[" + escape(r.key, actionKey) + ":" + value + "]"
+}
 func (r Push) String() string {
 	return fmt.Sprintf("Push<%v:%v>", r.key, r.value)
 }
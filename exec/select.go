@@ -10,10 +10,60 @@ func NewSelect(ops []Operation) Select {
 	return Select{ops: ops}
 }
 
+// Options are the operations the Select picks between
+func (r Select) Options() []Operation {
+	return r.ops
+}
+
+// Same reports whether both are copies of one Select, as copies share their
+// options
+func (r Select) Same(other Select) bool {
+	return len(r.ops) > 0 && len(other.ops) > 0 && &r.ops[0] == &other.ops[0]
+}
+
 func (r Select) Resolve(ctx Context) string {
-	i := ctx.Intn(len(r.ops))
+	return r.resolveFor(ctx, "")
+}
+
+// resolveFor resolves the Select as the rules of a symbol, for a Chooser
+func (r Select) resolveFor(ctx Context, symbol string) string {
+	var i int
+	if c, ok := ctx.(Chooser); ok {
+		i = c.Choose(symbol, r.ops)
+	} else {
+		i = ctx.Intn(len(r.ops))
+	}
+	if o, ok := ctx.(Observer); ok {
+		o.ObserveChoice(symbol, r, i)
+	}
 	return r.ops[i].Resolve(ctx)
 }
+
+// resolveFor resolves an operation as the rules of a symbol
+func resolveFor(op Operation, ctx Context, symbol string) string {
+	t, ok := ctx.(Tracer)
+	if ok {
+		t.Enter(symbol)
+	}
+
+	var out string
+	if sel, isSelect := op.(Select); isSelect {
+		out = sel.resolveFor(ctx, symbol)
+	} else {
+		out = op.Resolve(ctx)
+	}
+
+	if ok {
+		t.Leave()
+	}
+	return out
+}
+
+// Source of a Select is its options separated by commas, as they'd be written
+// in an action, e.g. `a,b` from `[x:a,b]`
+func (r Select) Source() string {
+	return source(r, actionValue)
+}
 func (r Select) String() string {
 	return fmt.Sprintf("Select<%d:%v>", len(r.ops), r.ops)
 }
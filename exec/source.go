@@ -0,0 +1,61 @@
+package exec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Characters which have to be escaped in each part of a rule to be read as text
+const (
+	topLevel    = `\#[{`
+	actionKey   = `\#[]:,`
+	actionValue = `\#[],{`
+	tagKey      = `\#[].()`
+	modParam    = `\#[(),{`
+	blockBody   = `\#[{}`
+)
+
+// source writes op out as rule text, escaping any literal text for the part
+// of the rule it appears in
+func source(op Operation, special string) string {
+	switch op := op.(type) {
+	case Literal:
+		return escape(op.value, special)
+	case Concat:
+		var out strings.Builder
+		for _, rule := range op.rules {
+			out.WriteString(source(rule, special))
+		}
+		return out.String()
+	case Select:
+		var options []string
+		for _, option := range op.ops {
+			options = append(options, source(option, special))
+		}
+		return strings.Join(options, ",")
+	}
+	return Source(op)
+}
+
+// Source gives the rule text which parses to op if it's a Sourcer, or else
+// its %v, which won't parse back to it
+func Source(op Operation) string {
+	if s, ok := op.(Sourcer); ok {
+		return s.Source()
+	}
+	return fmt.Sprint(op)
+}
+
+func escape(value string, special string) string {
+	if !strings.ContainsAny(value, special) {
+		return value
+	}
+	var out strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(special, r) {
+			out.WriteByte('\\')
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
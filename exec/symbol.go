@@ -1,6 +1,9 @@
 package exec
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type ModCall struct {
 	key    string
@@ -15,13 +18,25 @@ func NewModCall(key string, params []Operation) ModCall {
 	return ModCall{key: key, params: params}
 }
 
+func (r ModCall) Source() string {
+	out := "." + escape(r.key, tagKey)
+	if r.params == nil {
+		return out
+	}
+	var params []string
+	for _, param := range r.params {
+		params = append(params, source(param, modParam))
+	}
+	return out + "(" + strings.Join(params, ",") + ")"
+}
 func (r ModCall) String() string {
 	return fmt.Sprintf("ModCall	<%v:%d:%v>", r.key, len(r.params), r.params)
 }
 
 type Symbol struct {
-	key  string
-	mods []ModCall
+	key     string
+	mods    []ModCall
+	actions []Operation
 }
 
 func NewSymbol(key string) Symbol {
@@ -31,13 +46,53 @@ func NewSymbolWithMods(key string, mods []ModCall) Symbol {
 	return Symbol{key: key, mods: mods}
 }
 
+// NewSymbolWithActions makes a symbol which runs actions before it expands,
+// e.g. `#[hero:#name#]story#`. Pushes made by the actions are undone once the
+// symbol has expanded
+func NewSymbolWithActions(actions []Operation, key string, mods []ModCall) Symbol {
+	return Symbol{key: key, mods: mods, actions: actions}
+}
+
 func (r Symbol) Resolve(ctx Context) string {
+	var undo []Operation
+	hold(ctx)
+	for _, action := range r.actions {
+		if push, ok := action.(Push); ok {
+			// A pop won't remove the last rule, so clear instead if there wasn't one
+			if ctx.Lookup(push.key) == nil {
+				undo = append(undo, NewClear(push.key))
+			} else {
+				undo = append(undo, NewPop(push.key))
+			}
+		}
+		action.Resolve(ctx)
+	}
+	release(ctx)
+
+	out := r.expand(ctx)
+
+	for i := len(undo) - 1; i >= 0; i-- {
+		undo[i].Resolve(ctx)
+	}
+	return out
+}
+
+func (r Symbol) expand(ctx Context) string {
 	value := ctx.Lookup(r.key)
 	if value == nil {
-		return "((" + r.key + "))"
+		return write(ctx, "(("+r.key+"))")
+	}
+	if o, ok := ctx.(Observer); ok {
+		o.ObserveSymbol(r.key)
+	}
+
+	if len(r.mods) == 0 {
+		return resolveFor(value, ctx, r.key)
 	}
 
-	out := value.Resolve(ctx)
+	// Modifiers change the value, so it's only output once they're done
+	hold(ctx)
+	out := resolveFor(value, ctx, r.key)
 
 	for _, mod := range r.mods {
 		m, ok := ctx.LookupModifier(mod.key)
@@ -52,10 +107,28 @@ func (r Symbol) Resolve(ctx Context) string {
 		}
 
 		out = m.Modify(out, params...)
+		if o, ok := ctx.(Observer); ok {
+			o.ObserveModifier(mod.key)
+		}
 	}
+	release(ctx)
 
-	return out
+	return write(ctx, out)
+}
+func (r Symbol) Source() string {
+	out := "#"
+	for _, action := range r.actions {
+		out += Source(action)
+	}
+	out += escape(r.key, tagKey)
+	for _, mod := range r.mods {
+		out += mod.Source()
+	}
+	return out + "#"
 }
 func (r Symbol) String() string {
+	if len(r.actions) > 0 {
+		return fmt.Sprintf("Symbol<%v:%d:%v:%v>", r.key, len(r.mods), r.mods, r.actions)
+	}
 	return fmt.Sprintf("Symbol<%v:%d:%v>", r.key, len(r.mods), r.mods)
 }
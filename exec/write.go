@@ -0,0 +1,33 @@
+package exec
+
+// Writer is optionally implemented by a Context which wants the output as it's
+// made, rather than only once the expansion is done, e.g. to give up early
+type Writer interface {
+	// Write is given each piece of the output, in order
+	Write(s string)
+	// Hold stops writes until the matching Release. It's used while making
+	// text which isn't output as is, e.g. an action's value, or a symbol's
+	// value before its modifiers
+	Hold()
+	Release()
+}
+
+// write passes output on to the context, if it's a Writer
+func write(ctx Context, s string) string {
+	if w, ok := ctx.(Writer); ok {
+		w.Write(s)
+	}
+	return s
+}
+
+func hold(ctx Context) {
+	if w, ok := ctx.(Writer); ok {
+		w.Hold()
+	}
+}
+
+func release(ctx Context) {
+	if w, ok := ctx.(Writer); ok {
+		w.Release()
+	}
+}
@@ -2,6 +2,8 @@ package tracery
 
 import (
 	"math/rand"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/martletandco/tracery-go/exec"
@@ -9,9 +11,35 @@ import (
 )
 
 type Grammar struct {
-	Rand      func(n int) int
-	value     map[string][]exec.Operation
+	// Rand picks between options, unless an RNG is set with SetRNG
+	Rand func(n int) int
+	// Chooser, when set, picks between options instead of Rand
+	Chooser Chooser
+	// PathSeeded picks each option from Seed and the derivation path of the
+	// choice, e.g. `origin/0/animal`, instead of from Rand. Editing one part of
+	// the grammar then leaves choices elsewhere as they were, though every
+	// Flatten of the same input gives the same output until Seed is changed
+	PathSeeded bool
+	Seed       int64
+	// Persistent keeps the effects of inline actions, e.g. `[hero:Ada]`, after
+	// Flatten returns so they carry on into later calls. By default each call
+	// starts from the same rules
+	Persistent bool
+	// Coverage, when set, counts the options chosen and modifiers called by
+	// every Flatten. Clones share it
+	Coverage  *exec.Coverage
+	value     map[string]*stack
+	base      map[string]*stack
 	modifiers map[string]exec.Modifier
+	sources   map[string][]string
+	// pins maps a symbol to the source of the option it always picks
+	pins map[string]string
+	// rng, when set by SetRNG, picks options instead of Rand
+	rng RNG
+	// shared is set when the maps above may also belong to a clone. It's
+	// shared with clones until they copy the maps, so it's atomic to let
+	// clones be made from more than one goroutine
+	shared *atomic.Bool
 }
 
 func NewGrammar() Grammar {
@@ -20,15 +48,30 @@ func NewGrammar() Grammar {
 
 	return Grammar{
 		Rand:      r.Intn,
-		value:     make(map[string][]exec.Operation),
+		value:     make(map[string]*stack),
+		base:      make(map[string]*stack),
 		modifiers: make(map[string]exec.Modifier),
+		sources:   make(map[string][]string),
+		pins:      make(map[string]string),
+		shared:    new(atomic.Bool),
 	}
 }
 
-// Flatten resolves a grammar tree
+// Flatten resolves a grammar tree. Inline actions only last for the call,
+// unless the grammar is Persistent
 func (g *Grammar) Flatten(input string) string {
-	tree := parse.String(input)
-	return tree.Resolve(g)
+	out, s := g.flatten(parse.String(input))
+	if g.Persistent {
+		s.commit()
+	}
+	return out
+}
+
+// flatten resolves a parsed tree in a new scope, which is given back so the
+// caller can decide whether to commit it
+func (g *Grammar) flatten(tree exec.Operation) (string, *scope) {
+	s := newScope(g)
+	return tree.Resolve(s), s
 }
 
 // PushRule pushes a rule to a symbol. If more than one rule is supplied then one
@@ -37,7 +80,7 @@ func (g *Grammar) Flatten(input string) string {
 // JSON string arrays
 func (g *Grammar) PushRule(key string, rules ...string) {
 	op := parse.Strings(rules)
-	g.Push(key, op)
+	g.pushBase(key, op)
 }
 
 // PushRules differs from PushRule in that multiple rules will be treated as
@@ -45,17 +88,112 @@ func (g *Grammar) PushRule(key string, rules ...string) {
 func (g *Grammar) PushRules(key string, rules ...string) {
 	for _, rule := range rules {
 		op := parse.String(rule)
-		g.Push(key, op)
+		g.pushBase(key, op)
 	}
 }
 
+// PushRuleSet pushes every rule in the set. Symbols are pushed in sorted order
+// so the result doesn't depend on map order
 func (g *Grammar) PushRuleSet(set RuleSet) {
-	for key, rules := range set {
-		g.PushRule(key, rules...)
+	for _, key := range set.Keys() {
+		g.PushRule(key, set[key]...)
+	}
+}
+
+// PushOrderedRuleSet pushes every rule in the set, in the order they were defined
+func (g *Grammar) PushOrderedRuleSet(set OrderedRuleSet) {
+	for _, entry := range set {
+		g.PushRule(entry.Key, entry.Rule...)
+	}
+}
+
+// pushBase pushes a rule which is kept through a Reset, unlike a rule pushed
+// inline or by calling Push directly
+func (g *Grammar) pushBase(key string, op exec.Operation) {
+	g.own()
+	g.base[key] = g.base[key].push(op)
+	g.Push(key, op)
+}
+
+// Clone makes an independent copy of the grammar, which can have rules and
+// modifiers added or removed without affecting the original, and vice versa.
+// Cloning is cheap however large the grammar: the two share everything until
+// one of them changes, at which point it copies its map of symbols, but not
+// the rules themselves.
+//
+// Clones can be made from more than one goroutine at once, e.g. a variant per
+// request, so long as nothing changes the grammar meanwhile. A clone shares the
+// grammar's Rand, which isn't safe to use from more than one goroutine, so
+// give each clone its own Rand or RNG before using them concurrently
+func (g *Grammar) Clone() Grammar {
+	if g.shared == nil {
+		// Only a Grammar not made by NewGrammar has no flag yet
+		g.shared = new(atomic.Bool)
 	}
+	g.shared.Store(true)
+	return *g
+}
+
+// own gives the grammar its own maps if they might be shared with a clone
+func (g *Grammar) own() {
+	if g.shared == nil {
+		g.shared = new(atomic.Bool)
+	}
+	if !g.shared.Load() {
+		return
+	}
+	value := make(map[string]*stack, len(g.value))
+	for key, rules := range g.value {
+		value[key] = rules
+	}
+	base := make(map[string]*stack, len(g.base))
+	for key, rules := range g.base {
+		base[key] = rules
+	}
+	modifiers := make(map[string]exec.Modifier, len(g.modifiers))
+	for key, mod := range g.modifiers {
+		modifiers[key] = mod
+	}
+	sources := make(map[string][]string, len(g.sources))
+	for key, files := range g.sources {
+		sources[key] = files
+	}
+	pins := make(map[string]string, len(g.pins))
+	for key, option := range g.pins {
+		pins[key] = option
+	}
+	g.value, g.base, g.modifiers, g.sources, g.pins = value, base, modifiers, sources, pins
+	// The flag is still shared with clones, so this grammar gets its own
+	g.shared = new(atomic.Bool)
+}
+
+// Delete removes a symbol, its rules and where they were loaded from. Unlike
+// Clear the symbol does not come back with a Reset
+func (g *Grammar) Delete(key string) {
+	g.own()
+	delete(g.value, key)
+	delete(g.base, key)
+	delete(g.sources, key)
+}
+
+// Reset undoes every push, pop and clear made inline on a Persistent grammar or
+// by calling Push, Pop and Clear directly, leaving each symbol with the
+// rules given to PushRule, PushRules and PushRuleSet
+func (g *Grammar) Reset() {
+	g.own()
+	g.value = make(map[string]*stack, len(g.base))
+	for key, rules := range g.base {
+		g.value[key] = rules
+	}
+}
+
+// Sources lists the files a symbol's rules were loaded from, if any
+func (g *Grammar) Sources(key string) []string {
+	return g.sources[key]
 }
 
 func (g *Grammar) AddModifier(name string, mod exec.Modifier) {
+	g.own()
 	g.modifiers[name] = mod
 }
 
@@ -64,34 +202,78 @@ func (g *Grammar) AddModifyFunc(name string, mod func(value string, params ...st
 	g.AddModifier(name, ModifierFunc(mod))
 }
 
-// Context implementation below
+// Symbols lists every symbol with rules, in sorted order
+func (g *Grammar) Symbols() []string {
+	keys := make([]string, 0, len(g.value))
+	for key := range g.value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
 
-func (c *Grammar) Lookup(key string) exec.Operation {
-	rules, ok := c.value[key]
-	if !ok {
+// HasSymbol reports whether a symbol has any rules
+func (g *Grammar) HasSymbol(key string) bool {
+	_, ok := g.value[key]
+	return ok
+}
+
+// StackDepth is the number of rules pushed to a symbol and not yet popped
+func (g *Grammar) StackDepth(key string) int {
+	return g.value[key].len()
+}
+
+// Rules gives the source of the options the symbol currently picks from, i.e.
+// the rules of the most recent push
+func (g *Grammar) Rules(key string) []string {
+	op := g.Lookup(key)
+	if op == nil {
 		return nil
 	}
-	return rules[len(rules)-1]
+	return sources(op)
 }
-func (c *Grammar) Push(key string, value exec.Operation) {
-	rules, ok := c.value[key]
-	if !ok {
-		c.value[key] = []exec.Operation{value}
-		return
+
+// Modifiers lists the names of every modifier, in sorted order
+func (g *Grammar) Modifiers() []string {
+	keys := make([]string, 0, len(g.modifiers))
+	for key := range g.modifiers {
+		keys = append(keys, key)
 	}
-	c.value[key] = append(rules, value)
+	sort.Strings(keys)
+	return keys
+}
+
+// Context implementation below
+
+func (c *Grammar) Lookup(key string) exec.Operation {
+	return c.value[key].top()
+}
+func (c *Grammar) Push(key string, value exec.Operation) {
+	c.own()
+	c.value[key] = c.value[key].push(value)
 }
 func (c *Grammar) Pop(key string) {
-	rules, ok := c.value[key]
-	if !ok || len(rules) == 1 {
-		// Nothing left to pop (there is a different action to clear)
+	rules := c.value[key]
+	if rules.len() <= 1 {
+		// Nothing left to pop (use Clear, or [key:CLEAR], to remove the last rule)
 		// @enhance: warning about empty stack?
 		return
 	}
 
-	c.value[key] = rules[:len(rules)-1]
+	c.own()
+	c.value[key] = rules.next
+}
+
+// Clear removes every rule from a symbol, leaving it unset until either more
+// rules are pushed or the grammar is Reset
+func (c *Grammar) Clear(key string) {
+	c.own()
+	delete(c.value, key)
 }
 func (c *Grammar) Intn(n int) int {
+	if c.rng != nil {
+		return c.rng.Intn(n)
+	}
 	return c.Rand(n)
 }
 
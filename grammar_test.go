@@ -1,6 +1,10 @@
 package tracery
 
-import "testing"
+import (
+	"strings"
+	"sync"
+	"testing"
+)
 
 /**
 Literals
@@ -103,6 +107,36 @@ func TestFlattenPushAndReadInline(t *testing.T) {
 	})
 }
 
+func TestFlattenNested(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected string
+	}{
+		{"[a:[b:x]#b#y]#a# #b#", "xy x"},
+		{"[a:[b:[c:z]#c#]#b#]#a##c#", "zz"},
+		{`[a:\#b\#\, \[c\]]#a#`, "#b#, [c]"},
+		{`[a:\\]#a#`, `\`},
+		{"[x:a]#x.wrap(<,>)#", "<a>"},
+		{`[x:a]#x.wrap(\(,\))#`, "(a)"},
+		{"[x:a]#x.wrap((,))#", "(,)a"},
+		{"[x:a]#x.wrap([y:b]#y#,#y#)#", "bab"},
+	}
+
+	for _, tt := range tests {
+		g := NewGrammar()
+		g.AddModifyFunc("wrap", func(value string, params ...string) string {
+			if len(params) == 1 {
+				return params[0] + value
+			}
+			return params[0] + value + params[1]
+		})
+		got := g.Flatten(tt.input)
+		if got != tt.expected {
+			t.Errorf("Flatten(%v): got '%s' want '%s'", tt.input, got, tt.expected)
+		}
+	}
+}
+
 func TestFlattenPushAndReadContext(t *testing.T) {
 	t.Run("it returns a literal assigned and read from a symbol", func(t *testing.T) {
 		g := NewGrammar()
@@ -206,6 +240,263 @@ func TestFlattenPop(t *testing.T) {
 	})
 }
 
+func TestFlattenTagActions(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"it pushes a literal inside a tag", "[num:2]#[num:3]count#", "3"},
+		{"it pushes a symbol inside a tag", "[two:4][num:1]#[num:#two#]count#", "4"},
+		{"it reads the outer value when pushing a symbol to itself", "[num:1]#[num:#num#]num#", "1"},
+		{"it has no effect on values already expanded", "[num:2][total:#count#]#[num:3]total#", "2"},
+		{"it runs more than one action", "#[hero:Ada][pet:Rex]story#", "Ada and Rex"},
+		{"it pops pushes after the tag", "[num:1]#[num:2]num# #num#", "2 1"},
+		{"it clears pushes to new symbols after the tag", "#[num:2]num# #num#", "2 ((num))"},
+		{"it pops each push to the same symbol", "[num:1]#[num:2][num:3]num##num#", "31"},
+		{"it keeps pushes made while expanding", "#[hero:Ada]setup# #pet# #hero#", "Ada Rex ((hero))"},
+		{"it keeps actions for modifier params", "[x:a]#[p:b]x.join(#p#)# #p#", "ab ((p))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGrammar()
+			g.PushRule("count", "#num#")
+			g.PushRule("story", "#hero# and #pet#")
+			g.PushRule("setup", "[pet:Rex]#hero#")
+			g.AddModifyFunc("join", func(value string, params ...string) string {
+				return value + strings.Join(params, "")
+			})
+			got := g.Flatten(tt.input)
+			if got != tt.expected {
+				t.Errorf("got '%s' want '%s'", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFlattenCallActions(t *testing.T) {
+	t.Run("it expands a tag for its actions without any text", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("setPronouns", "[they:she][them:her]", "[they:he][them:him]")
+		g.Rand = func(n int) int { return 0 }
+		got := g.Flatten("[#setPronouns#]#they# lost #them# hat")
+		want := "she lost her hat"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it ignores text produced by the tag", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("setup", "[hero:Ada]lots of text")
+		got := g.Flatten("[#setup#]#hero#")
+		want := "Ada"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it keeps a call's pushes after a tag, as only pushes are undone", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("setHero", "[hero:#name#][heroPet:#animal#]")
+		g.PushRule("name", "Ada", "Bo")
+		g.PushRule("animal", "cat", "dog")
+		g.PushRule("story", "#hero# and #heroPet#")
+		g.Rand = func(n int) int { return n - 1 }
+		got := g.Flatten("#[#setHero#]story#, #hero#")
+		want := "Bo and dog, Bo"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+}
+
+func TestFlattenClear(t *testing.T) {
+	t.Run("it clears every rule of a symbol inline", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRules("x", "a", "b")
+		got := g.Flatten("[x:c]#x#[x:CLEAR]#x#[x:d]#x#")
+		want := "c((x))d"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it clears every rule of a symbol", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRules("x", "a", "b")
+		g.Clear("x")
+		got := g.Flatten("#x#")
+		want := "((x))"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		if g.HasSymbol("x") {
+			t.Errorf("expected x to be cleared")
+		}
+	})
+}
+
+func TestFlattenScope(t *testing.T) {
+	t.Run("it forgets inline actions between calls", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRules("x", "a", "b")
+		g.PushRule("y", "c")
+		got := g.Flatten("[x:POP][x:d][y:CLEAR][z:e]#x##y##z#")
+		want := "d((y))e"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		got = g.Flatten("#x##y##z#")
+		want = "bc((z))"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		if g.StackDepth("x") != 2 {
+			t.Errorf("got depth %d want 2", g.StackDepth("x"))
+		}
+	})
+	t.Run("it keeps inline actions between calls when persistent", func(t *testing.T) {
+		g := NewGrammar()
+		g.Persistent = true
+		g.PushRules("x", "a", "b")
+		g.PushRule("y", "c")
+		got := g.Flatten("[x:POP][x:d][y:CLEAR][z:e]#x##y##z#")
+		want := "d((y))e"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		got = g.Flatten("#x##y##z#[x:POP]")
+		want = "d((y))e"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		got = g.Flatten("#x#")
+		want = "a"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it doesn't change the grammar's stack when popping and pushing", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRules("x", "a", "b")
+		got := g.Flatten("[x:POP][x:c]#x#")
+		want := "c"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		got = g.Flatten("#x#[x:POP]#x#")
+		want = "ba"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+}
+
+func TestReset(t *testing.T) {
+	t.Run("it restores rules after inline actions", func(t *testing.T) {
+		g := NewGrammar()
+		g.Persistent = true
+		g.PushRules("x", "a", "b")
+		g.PushRule("y", "c")
+		g.Flatten("[x:POP][x:POP][y:d][y:e][z:f]")
+		g.Reset()
+		got := g.Flatten("#x##y##z#")
+		want := "bc((z))"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		if g.StackDepth("x") != 2 {
+			t.Errorf("got depth %d want 2", g.StackDepth("x"))
+		}
+	})
+	t.Run("it restores cleared symbols", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("x", "a")
+		g.Clear("x")
+		g.Reset()
+		got := g.Flatten("#x#")
+		want := "a"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it doesn't restore deleted symbols", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("x", "a")
+		g.PushRule("y", "b")
+		g.Delete("x")
+		g.Reset()
+		got := g.Flatten("#x##y#")
+		want := "((x))b"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+}
+
+func TestClone(t *testing.T) {
+	assert := func(t *testing.T, got, want interface{}) {
+		if got != want {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+	}
+	t.Run("it keeps changes to a clone out of the original", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRules("x", "a", "b")
+		c := g.Clone()
+		c.PushRule("x", "c")
+		c.PushRule("y", "d")
+		c.AddModifyFunc("up", func(value string, params ...string) string { return strings.ToUpper(value) })
+		assert(t, c.Flatten("#x##y.up#"), "cD")
+		assert(t, g.Flatten("#x##y.up#"), "b((y))")
+		assert(t, g.StackDepth("x"), 2)
+	})
+	t.Run("it keeps changes to the original out of a clone", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRules("x", "a", "b")
+		c := g.Clone()
+		g.Pop("x")
+		g.PushRule("y", "d")
+		assert(t, g.Flatten("#x##y#"), "ad")
+		assert(t, c.Flatten("#x##y#"), "b((y))")
+	})
+	t.Run("it keeps clones of clones apart", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("x", "a")
+		c1 := g.Clone()
+		c2 := c1.Clone()
+		c1.Persistent = true
+		c1.Flatten("[x:b]")
+		c2.Delete("x")
+		assert(t, g.Flatten("#x#"), "a")
+		assert(t, c1.Flatten("#x#"), "b")
+		assert(t, c2.Flatten("#x#"), "((x))")
+		c1.Reset()
+		assert(t, c1.Flatten("#x#"), "a")
+	})
+	t.Run("it can be cloned from more than one goroutine", func(t *testing.T) {
+		// Run with -race to check
+		g := NewGrammar()
+		g.PushRule("x", "a", "b")
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					c := g.Clone()
+					c.SetRNG(NewPCG(uint64(i), 0))
+					c.PushRule("y", "c")
+					c.Persistent = true
+					c.Flatten("[x:d]#x##y#")
+				}
+			}(i)
+		}
+		wg.Wait()
+		g.Rand = func(n int) int { return 0 }
+		assert(t, g.Flatten("#x##y#"), "a((y))")
+	})
+}
+
 /**
 Rule select
 */
@@ -469,3 +760,47 @@ Pop
 
 
 */
+
+/**
+Introspection
+*/
+func TestIntrospection(t *testing.T) {
+	assert := func(t *testing.T, got, want interface{}) {
+		if got != want {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+	}
+	g := NewGrammar()
+	g.PushRule("pet", "fox", "#colour# dog", `\#1 cat`)
+	g.PushRules("colour", "red", "blue")
+	g.AddModifyFunc("shout", func(value string, params ...string) string { return value })
+	g.AddModifyFunc("a", func(value string, params ...string) string { return value })
+
+	t.Run("it lists symbols in order", func(t *testing.T) {
+		assert(t, strings.Join(g.Symbols(), ","), "colour,pet")
+	})
+	t.Run("it lists modifiers in order", func(t *testing.T) {
+		assert(t, strings.Join(g.Modifiers(), ","), "a,shout")
+	})
+	t.Run("it knows which symbols exist", func(t *testing.T) {
+		assert(t, g.HasSymbol("pet"), true)
+		assert(t, g.HasSymbol("cat"), false)
+	})
+	t.Run("it counts pushed rules", func(t *testing.T) {
+		assert(t, g.StackDepth("pet"), 1)
+		assert(t, g.StackDepth("colour"), 2)
+		assert(t, g.StackDepth("cat"), 0)
+	})
+	t.Run("it gives the source of the current rules", func(t *testing.T) {
+		assert(t, strings.Join(g.Rules("pet"), "|"), `fox|#colour# dog|\#1 cat`)
+		assert(t, strings.Join(g.Rules("colour"), "|"), "blue")
+		assert(t, len(g.Rules("cat")), 0)
+	})
+	t.Run("it gives the source of inline pushes", func(t *testing.T) {
+		g := NewGrammar()
+		g.Persistent = true
+		g.PushRule("text", `\#1, or \[2]`)
+		g.Flatten("[x:#text#]")
+		assert(t, strings.Join(g.Rules("x"), "|"), `\#1, or \[2]`)
+	})
+}
@@ -0,0 +1,168 @@
+package tracery
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// History is a record of past outputs kept in a file, so a bot run on a
+// schedule can avoid repeating itself. Only hashes of the outputs are kept,
+// one JSON object per line
+type History struct {
+	// Window is how long an output counts as seen, or forever if 0
+	Window time.Duration
+	// Similarity, when above 0, also counts an output as seen when it's at
+	// least this similar to a past output. It's estimated from a simhash of
+	// the words, so 1 is the same words and unrelated outputs are about 0.5
+	Similarity float64
+	path       string
+	entries    []historyEntry
+	now        func() time.Time
+}
+
+type historyEntry struct {
+	Hash    string    `json:"hash"`
+	Simhash string    `json:"simhash"`
+	Time    time.Time `json:"time"`
+	simhash uint64
+}
+
+// OpenHistory reads the history kept in a file. The file needn't exist yet,
+// it's created by the first Add
+func OpenHistory(path string) (*History, error) {
+	h := &History{path: path, now: time.Now}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %v", path, line, err)
+		}
+		entry.simhash, err = strconv.ParseUint(entry.Simhash, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: bad simhash %q", path, line, entry.Simhash)
+		}
+		h.entries = append(h.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Seen reports whether the output, or one similar enough to it, was added
+// within the window
+func (h *History) Seen(out string) bool {
+	hash := hashOutput(out)
+	sim := simhash(out)
+	now := h.now()
+	for _, entry := range h.entries {
+		if h.Window > 0 && now.Sub(entry.Time) > h.Window {
+			continue
+		}
+		if entry.Hash == hash {
+			return true
+		}
+		if h.Similarity > 0 && similarity(entry.simhash, sim) >= h.Similarity {
+			return true
+		}
+	}
+	return false
+}
+
+// Add records the output, writing it to the end of the file
+func (h *History) Add(out string) error {
+	entry := historyEntry{Hash: hashOutput(out), simhash: simhash(out), Time: h.now().UTC()}
+	entry.Simhash = fmt.Sprintf("%016x", entry.simhash)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+// Unseen is a constraint for FlattenMeeting which rejects outputs Seen before
+func (h *History) Unseen() Constraint {
+	return Constraint{
+		Reason: "not seen before",
+		Check:  func(out Output) bool { return !h.Seen(out.Text) },
+	}
+}
+
+func hashOutput(out string) string {
+	sum := sha256.Sum256([]byte(out))
+	return hex.EncodeToString(sum[:])
+}
+
+// simhash of the words in the output, ignoring case and punctuation. Outputs
+// sharing most of their words have hashes differing in only a few bits
+func simhash(out string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(out), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	var weights [64]int
+	for _, word := range words {
+		f := fnv.New64a()
+		f.Write([]byte(word))
+		hash := f.Sum64()
+		for i := range weights {
+			if hash&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	var hash uint64
+	for i, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// similarity of two simhashes, from 0 to 1
+func similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
@@ -0,0 +1,78 @@
+package tracery
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	open := func(t *testing.T, path string, now *time.Time) *History {
+		h, err := OpenHistory(path)
+		if err != nil {
+			t.Fatalf("OpenHistory: encountered error: %v", err)
+		}
+		h.now = func() time.Time { return *now }
+		return h
+	}
+
+	t.Run("it remembers outputs between runs", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		h := open(t, path, &now)
+		if h.Seen("a fox") {
+			t.Errorf("expected nothing to be seen yet")
+		}
+		if err := h.Add("a fox"); err != nil {
+			t.Fatalf("Add: encountered error: %v", err)
+		}
+
+		h = open(t, path, &now)
+		if !h.Seen("a fox") || h.Seen("a dog") {
+			t.Errorf("expected only 'a fox' to be seen")
+		}
+	})
+	t.Run("it forgets outputs outside the window", func(t *testing.T) {
+		now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		h := open(t, filepath.Join(t.TempDir(), "history.jsonl"), &now)
+		h.Window = 30 * 24 * time.Hour
+		h.Add("a fox")
+
+		now = now.Add(29 * 24 * time.Hour)
+		if !h.Seen("a fox") {
+			t.Errorf("expected 'a fox' to be seen within the window")
+		}
+		now = now.Add(2 * 24 * time.Hour)
+		if h.Seen("a fox") {
+			t.Errorf("expected 'a fox' to be forgotten after the window")
+		}
+	})
+	t.Run("it rejects similar outputs", func(t *testing.T) {
+		now := time.Now()
+		h := open(t, filepath.Join(t.TempDir(), "history.jsonl"), &now)
+		h.Add("The quick brown fox jumped over the lazy dog")
+		if h.Seen("the quick brown fox jumped over the lazy dog!") {
+			t.Errorf("expected only exact outputs to be seen without a similarity")
+		}
+		h.Similarity = 1
+		if !h.Seen("the quick brown fox jumped over the lazy dog!") {
+			t.Errorf("expected the same words to be seen")
+		}
+		if h.Seen("Pack my box with five dozen liquor jugs") {
+			t.Errorf("expected different words not to be seen")
+		}
+	})
+	t.Run("it is a constraint for FlattenMeeting", func(t *testing.T) {
+		now := time.Now()
+		h := open(t, filepath.Join(t.TempDir(), "history.jsonl"), &now)
+		h.Add("fox")
+		g := NewGrammar()
+		g.PushRule("animal", "fox", "dog")
+		for i := 0; i < 10; i++ {
+			got, err := g.FlattenMeeting("#animal#", 100, h.Unseen())
+			if err != nil || got != "dog" {
+				t.Errorf("got '%s' (%v) want 'dog'", got, err)
+			}
+		}
+	})
+}
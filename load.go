@@ -0,0 +1,169 @@
+package tracery
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// MergePolicy decides what happens when more than one file defines the same symbol
+type MergePolicy int
+
+const (
+	// MergeError refuses to load a symbol that has already been defined by another file
+	MergeError MergePolicy = iota
+	// MergeOverride replaces the rules of an earlier file with those of the later one
+	MergeOverride
+	// MergeAppend adds the rules of the later file as extra options of the earlier one
+	MergeAppend
+)
+
+// Loader reads grammar files and merges them into a single Grammar. The zero
+// value is ready to use and refuses conflicting symbols
+type Loader struct {
+	Policy MergePolicy
+}
+
+// LoadFile reads a grammar from a file, or from all the grammar files in a
+// directory. The format of each file is chosen by its extension, see FormatOf
+func LoadFile(path string) (Grammar, error) {
+	return Loader{}.LoadFiles(path)
+}
+
+// LoadFiles reads and merges grammars from files and directories, in the order given
+func LoadFiles(paths ...string) (Grammar, error) {
+	return Loader{}.LoadFiles(paths...)
+}
+
+// LoadFS reads and merges all the grammars in fsys matching glob, e.g. from an
+// embed.FS. Matches which aren't in a known grammar format are skipped
+func LoadFS(fsys fs.FS, glob string) (Grammar, error) {
+	return Loader{}.LoadFS(fsys, glob)
+}
+
+func (l Loader) LoadFiles(paths ...string) (Grammar, error) {
+	m := newMerger(l.Policy)
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return NewGrammar(), err
+		}
+		if info.IsDir() {
+			if err := m.addFS(os.DirFS(p), "*", p); err != nil {
+				return NewGrammar(), err
+			}
+			continue
+		}
+
+		format, ok := FormatOf(p)
+		if !ok {
+			return NewGrammar(), fmt.Errorf("%s: unknown grammar format", p)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return NewGrammar(), err
+		}
+		if err := m.add(p, format, data); err != nil {
+			return NewGrammar(), err
+		}
+	}
+	return m.grammar(), nil
+}
+
+func (l Loader) LoadFS(fsys fs.FS, glob string) (Grammar, error) {
+	m := newMerger(l.Policy)
+	if err := m.addFS(fsys, glob, ""); err != nil {
+		return NewGrammar(), err
+	}
+	return m.grammar(), nil
+}
+
+// merger collects rule sets from many files, applying a MergePolicy as it goes
+// so that conflicts can be reported against the files they came from
+type merger struct {
+	policy  MergePolicy
+	keys    []string
+	rules   map[string]Rule
+	sources map[string][]string
+}
+
+func newMerger(policy MergePolicy) *merger {
+	return &merger{
+		policy:  policy,
+		rules:   make(map[string]Rule),
+		sources: make(map[string][]string),
+	}
+}
+
+// addFS adds every file in fsys matching glob. Names are reported relative to
+// dir so errors point somewhere useful
+func (m *merger) addFS(fsys fs.FS, glob string, dir string) error {
+	names, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			return err
+		}
+		format, ok := FormatOf(name)
+		if info.IsDir() || !ok {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if dir != "" {
+			name = filepath.Join(dir, filepath.FromSlash(name))
+		} else {
+			name = path.Clean(name)
+		}
+		if err := m.add(name, format, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *merger) add(name string, format string, data []byte) error {
+	set, err := ParseRuleSet(format, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	for _, entry := range set {
+		key, rule := entry.Key, entry.Rule
+		prev, ok := m.rules[key]
+		if !ok {
+			m.keys = append(m.keys, key)
+			m.rules[key] = rule
+			m.sources[key] = []string{name}
+			continue
+		}
+
+		switch m.policy {
+		case MergeOverride:
+			m.rules[key] = rule
+			m.sources[key] = []string{name}
+		case MergeAppend:
+			m.rules[key] = append(append(Rule{}, prev...), rule...)
+			m.sources[key] = append(m.sources[key], name)
+		default:
+			return fmt.Errorf("%s: symbol %q is already defined in %s", name, key, m.sources[key][0])
+		}
+	}
+	return nil
+}
+
+func (m *merger) grammar() Grammar {
+	g := NewGrammar()
+	for _, key := range m.keys {
+		g.PushRule(key, m.rules[key]...)
+		g.sources[key] = m.sources[key]
+	}
+	return g
+}
@@ -0,0 +1,125 @@
+package tracery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFS(t *testing.T) {
+	assert := func(t *testing.T, got, want interface{}) {
+		if got != want {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+	}
+	fsys := fstest.MapFS{
+		"grammars/animals.json": {Data: []byte(`{"animal": "fox"}`)},
+		"grammars/origin.json":  {Data: []byte(`{"origin": "the #animal#"}`)},
+		"grammars/more.json":    {Data: []byte(`{"animal": "emu"}`)},
+		"grammars/notes.txt":    {Data: []byte(`not a grammar`)},
+	}
+
+	t.Run("it loads a single file", func(t *testing.T) {
+		g, err := LoadFS(fsys, "grammars/origin.json")
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		assert(t, g.Flatten("#origin#"), "the ((animal))")
+	})
+	t.Run("it refuses conflicting symbols by default", func(t *testing.T) {
+		_, err := LoadFS(fsys, "grammars/*.json")
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+		if !strings.Contains(err.Error(), "grammars/animals.json") || !strings.Contains(err.Error(), "grammars/more.json") {
+			t.Errorf("expected error to name both files, got '%v'", err)
+		}
+	})
+	t.Run("it overrides conflicting symbols", func(t *testing.T) {
+		g, err := Loader{Policy: MergeOverride}.LoadFS(fsys, "grammars/*.json")
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		assert(t, g.Flatten("#origin#"), "the emu")
+		assert(t, strings.Join(g.Sources("animal"), ","), "grammars/more.json")
+	})
+	t.Run("it appends conflicting symbols", func(t *testing.T) {
+		g, err := Loader{Policy: MergeAppend}.LoadFS(fsys, "grammars/*.json")
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		g.Rand = func(n int) int { return n - 1 }
+		assert(t, g.Flatten("#animal#"), "emu")
+		g.Rand = func(n int) int { return 0 }
+		assert(t, g.Flatten("#animal#"), "fox")
+		assert(t, strings.Join(g.Sources("animal"), ","), "grammars/animals.json,grammars/more.json")
+	})
+	t.Run("it picks the format by file extension", func(t *testing.T) {
+		g, err := LoadFS(fstest.MapFS{
+			"origin.yaml":   {Data: []byte("origin: |-\n  #greeting#,\n  #name#")},
+			"greeting.toml": {Data: []byte(`greeting = 'hello'`)},
+			"name.json":     {Data: []byte(`{"name": "world"}`)},
+			"README.md":     {Data: []byte(`# Not a grammar`)},
+		}, "*")
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		assert(t, g.Flatten("#origin#"), "hello,\nworld")
+	})
+	t.Run("it names the file with invalid json", func(t *testing.T) {
+		_, err := LoadFS(fstest.MapFS{"bad.json": {Data: []byte(`{"x":`)}}, "*.json")
+		if err == nil || !strings.HasPrefix(err.Error(), "bad.json:") {
+			t.Errorf("expected error naming bad.json, got '%v'", err)
+		}
+	})
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	origin := write("origin.json", `{"origin": "#greeting#, #name#"}`)
+	write("greeting.json", `{"greeting": "hello"}`)
+
+	t.Run("it loads a file", func(t *testing.T) {
+		g, err := LoadFile(origin)
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		got := g.Flatten("#origin#")
+		want := "((greeting)), ((name))"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it loads a directory and merges in more files", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "name.json")
+		if err := os.WriteFile(name, []byte(`{"name": "world"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		g, err := LoadFiles(dir, name)
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		got := g.Flatten("#origin#")
+		want := "hello, world"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		if got := g.Sources("greeting"); len(got) != 1 || got[0] != filepath.Join(dir, "greeting.json") {
+			t.Errorf("got sources '%v'", got)
+		}
+	})
+	t.Run("it returns an error for a missing file", func(t *testing.T) {
+		if _, err := LoadFile(filepath.Join(dir, "missing.json")); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package tracery
+
+import "strings"
+
+// Media is an image taken out of a flattened rule by ExtractMedia
+type Media struct {
+	// Kind is either "svg" or "img"
+	Kind string
+	// Body is the SVG markup for svg, or the URL for img
+	Body string
+}
+
+// Result is flattened text with its media taken out, as a CBDQ bot would post it
+type Result struct {
+	Text  string
+	Media []Media
+}
+
+// FlattenResult flattens the input and then takes out any media blocks
+func (g *Grammar) FlattenResult(input string) Result {
+	return ExtractMedia(g.Flatten(input))
+}
+
+// ExtractMedia takes CBDQ's `{svg <svg ...>...</svg>}` and `{img url}` blocks
+// out of flattened text, in the order they appear. The remaining text has
+// surrounding whitespace trimmed. Any other braces are left in the text
+func ExtractMedia(text string) Result {
+	var r Result
+	var out strings.Builder
+
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := closingBrace(text, start)
+		if end < 0 {
+			break
+		}
+		media, ok := parseMedia(text[start+1 : end])
+		if ok {
+			out.WriteString(text[:start])
+			r.Media = append(r.Media, media)
+		} else {
+			out.WriteString(text[:end+1])
+		}
+		text = text[end+1:]
+	}
+	out.WriteString(text)
+
+	r.Text = strings.TrimSpace(out.String())
+	return r
+}
+
+// closingBrace finds the brace matching the one at start, counting any nested
+// in between, e.g. the braces of CSS in an SVG
+func closingBrace(text string, start int) int {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseMedia(block string) (Media, bool) {
+	for _, kind := range []string{"svg", "img"} {
+		if !strings.HasPrefix(block, kind+" ") {
+			continue
+		}
+		body := strings.TrimSpace(block[len(kind)+1:])
+		return Media{Kind: kind, Body: body}, true
+	}
+	return Media{}, false
+}
@@ -0,0 +1,49 @@
+package tracery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMedia(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected Result
+	}{
+		{"", Result{}},
+		{"just text", Result{Text: "just text"}},
+		{"look {img http://x.test/a.png}", Result{Text: "look", Media: []Media{{"img", "http://x.test/a.png"}}}},
+		{"{svg <svg></svg>} a {img b}", Result{Text: "a", Media: []Media{{"svg", "<svg></svg>"}, {"img", "b"}}}},
+		{"{svg <style>g {fill:red}</style>}", Result{Media: []Media{{"svg", "<style>g {fill:red}</style>"}}}},
+		{"a {b} c", Result{Text: "a {b} c"}},
+		{"a {svg b", Result{Text: "a {svg b"}},
+	}
+
+	for _, tt := range tests {
+		actual := ExtractMedia(tt.input)
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("ExtractMedia(%q): expected %v, actual %v", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestFlattenResult(t *testing.T) {
+	t.Run("it expands the body of a block without mangling it", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("colour", "red")
+		g.PushRule("origin", "A #colour# box {svg <svg><rect fill='#colour#' x='0,1'/></svg>}")
+		got := g.FlattenResult("#origin#")
+		want := Result{Text: "A red box", Media: []Media{{"svg", "<svg><rect fill='red' x='0,1'/></svg>"}}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+	})
+	t.Run("it keeps blocks whole inside actions", func(t *testing.T) {
+		g := NewGrammar()
+		got := g.Flatten("[pic:{img a,b}]#pic#")
+		want := "{img a,b}"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+}
@@ -7,33 +7,14 @@ import (
 	"github.com/martletandco/tracery-go/scan"
 )
 
-func String(input string) exec.Operation {
-	ops := []exec.Operation{}
-	var op exec.Operation
-	scanner := scan.New(input)
-	for {
-		token := scanner.Peek()
-		if token.Type == scan.EOF {
-			break
-		}
-		switch token.Type {
-		case scan.LeftBracket:
-			op = parseAction(scanner)
-		case scan.Octo:
-			op = parseTag(scanner)
-		default:
-			op = parseLiteral(scanner)
-		}
+// The parser is a single pass, recursive descent over the scanner's tokens.
+// Text is taken from the tokens as they are, so escapes (which the scanner has
+// already removed) are only ever applied once however deeply a rule is nested.
+// Unclosed tags, actions and params are closed by the end of the input
 
-		ops = append(ops, op)
-	}
-	if len(ops) == 0 {
-		return exec.NewLiteral("")
-	}
-	if len(ops) == 1 {
-		return ops[0]
-	}
-	return exec.NewConcat(ops)
+func String(input string) exec.Operation {
+	p := parser{s: scan.New(input)}
+	return p.rule(topLevel)
 }
 
 // Strings takes a list of inputs and always returns a single operation
@@ -57,125 +38,267 @@ func Strings(inputs []string) exec.Operation {
 	return exec.NewSelect(ops)
 }
 
-func parseAction(s *scan.Scanner) exec.Operation {
-	// @cleanup: whole lot of assume the input is valid here
+// context is the part of a rule being parsed, which decides the tokens that
+// end a run of text
+type context int
+
+const (
+	topLevel    context = iota
+	actionValue         // ends at , or ]
+	modParam            // ends at , or an unmatched )
+	blockBody           // ends at }, or where the context the block is in ends
+)
+
+type parser struct {
+	s *scan.Scanner
+	// blockIn is the context the outermost open block is in, as a block's
+	// body also ends where that context does, e.g. at the ] of an action
+	blockIn context
+	// escaped is set once an escaped word has been read, so an action can tell
+	// POP from `\POP`
+	escaped bool
+	// unclosed are the blocks already found to have no }, so they aren't read
+	// again each time the parser goes back over them
+	unclosed map[unclosedBlock]bool
+}
+
+type unclosedBlock struct {
+	pos int
+	in  context
+}
+
+// rule reads text, tags and actions until the end of the current context
+func (p *parser) rule(ctx context) exec.Operation {
+	return join(p.ops(ctx))
+}
+
+// join makes a single operation of a rule's parts
+func join(ops []exec.Operation) exec.Operation {
+	if len(ops) == 0 {
+		return exec.NewLiteral("")
+	}
+	if len(ops) == 1 {
+		return ops[0]
+	}
+	return exec.NewConcat(ops)
+}
+
+// ops reads the parts of a rule until the end of the current context
+func (p *parser) ops(ctx context) []exec.Operation {
+	ops := []exec.Operation{}
+	var text strings.Builder
+	// Only used in params, so `#a.b(c (d))#` has the param `c (d)`, and in
+	// blocks in params
+	depth := 0
+
+	flush := func() {
+		if text.Len() > 0 {
+			ops = append(ops, exec.NewLiteral(text.String()))
+			text.Reset()
+		}
+	}
+
+Loop:
+	for {
+		token := p.s.Peek()
+		switch {
+		case token.Type == scan.EOF || token.Type == scan.Error:
+			break Loop
+		case token.Type == scan.LeftBracket:
+			flush()
+			ops = append(ops, p.action())
+			continue
+		case token.Type == scan.Octo:
+			flush()
+			ops = append(ops, p.tag())
+			continue
+		case token.Type == scan.LeftBrace:
+			if block := p.block(ctx); block != nil {
+				flush()
+				ops = append(ops, block)
+				continue
+			}
+			// Unclosed, so the brace is just text
+		case ctx == blockBody && token.Type == scan.RightBrace:
+			break Loop
+		case ctx == blockBody && p.blockIn == actionValue && token.Type == scan.RightBracket:
+			break Loop
+		case ctx == blockBody && p.blockIn == modParam && token.Type == scan.LeftParen:
+			depth++
+		case ctx == blockBody && p.blockIn == modParam && token.Type == scan.RightParen:
+			if depth == 0 {
+				break Loop
+			}
+			depth--
+		case ctx == actionValue && (token.Type == scan.Comma || token.Type == scan.RightBracket):
+			break Loop
+		case ctx == modParam && token.Type == scan.Comma && depth == 0:
+			break Loop
+		case ctx == modParam && token.Type == scan.LeftParen:
+			depth++
+		case ctx == modParam && token.Type == scan.RightParen:
+			if depth == 0 {
+				break Loop
+			}
+			depth--
+		}
+		token = p.s.Next()
+		p.escaped = p.escaped || token.Escaped
+		text.WriteString(token.Value)
+	}
+	flush()
+	return ops
+}
+
+// action reads `[key:rule,rule]`, `[key:POP]`, `[key:CLEAR]` or `[#tag#]`
+func (p *parser) action() exec.Operation {
 	// Consume opening [
-	s.Next()
-	keyToken := s.Next()
-	key := keyToken.Value
-	// Consume :
-	s.Next()
-	var ops []exec.Operation
+	p.s.Next()
+
+	if p.s.Peek().Type == scan.Octo {
+		// [#tag#] expands the tag just for its actions
+		tag := p.tag()
+		p.expect(scan.RightBracket)
+		return exec.NewCall(tag)
+	}
+
+	key := p.text(scan.Colon, scan.RightBracket)
+	p.expect(scan.Colon)
 
-	var ruleParts []string
+	// An escaped `\POP` or `\CLEAR` is pushed as text
+	p.escaped = false
+	var ops []exec.Operation
 	for {
-		rawValue := s.Next()
-		if rawValue.Type == scan.RightBracket {
-			op := String(strings.Join(ruleParts, ""))
-			ops = append(ops, op)
+		ops = append(ops, p.rule(actionValue))
+		if p.s.Peek().Type != scan.Comma {
 			break
 		}
-		if rawValue.Value == "POP" {
-			// Consume closing ]
-			s.Next()
+		// Consume ,
+		p.s.Next()
+	}
+	p.expect(scan.RightBracket)
+
+	if len(ops) > 1 {
+		return exec.NewPush(key, exec.NewSelect(ops))
+	}
+	if lit, ok := ops[0].(exec.Literal); ok && !p.escaped {
+		switch lit.Value() {
+		case "POP":
 			return exec.NewPop(key)
+		case "CLEAR":
+			return exec.NewClear(key)
 		}
-		if rawValue.Type == scan.Comma {
-			op := String(strings.Join(ruleParts, ""))
-			ops = append(ops, op)
-			ruleParts = ruleParts[:0]
-			continue
-		}
-
-		ruleParts = append(ruleParts, rawValue.Value)
 	}
-	if len(ops) == 0 {
-		return exec.NewPush(key, exec.NewLiteral(""))
+	return exec.NewPush(key, ops[0])
+}
+
+// block reads `{body}`, where only tags and actions in the body are treated as
+// grammar. This keeps CBDQ's `{svg <path d="M0,0 1,1"/>}` in one piece, even
+// inside an action. A block with no } before the end of the action or param
+// it's in isn't a block, so nil is given back and the scanner is left at the
+// {, to be read again as text
+func (p *parser) block(ctx context) exec.Operation {
+	if ctx != blockBody {
+		outer := p.blockIn
+		p.blockIn = ctx
+		defer func() { p.blockIn = outer }()
 	}
-	if len(ops) == 1 {
-		return exec.NewPush(key, ops[0])
+	at := unclosedBlock{pos: p.s.Pos(), in: p.blockIn}
+	if p.unclosed[at] {
+		return nil
 	}
+	mark, escaped := p.s.Mark(), p.escaped
 
-	return exec.NewPush(key, exec.NewSelect(ops))
+	// Consume opening {
+	p.s.Next()
+	body := p.ops(blockBody)
+	if p.s.Peek().Type != scan.RightBrace {
+		p.s.Reset(mark)
+		p.escaped = escaped
+		if p.unclosed == nil {
+			p.unclosed = make(map[unclosedBlock]bool)
+		}
+		p.unclosed[at] = true
+		return nil
+	}
+	// Consume closing }
+	p.s.Next()
+	return exec.NewBlock(join(body))
 }
 
-func parseTag(s *scan.Scanner) exec.Operation {
-	// @cleanup: whole lot of assume the input is valid here
+// tag reads `#[action]key.mod.mod(param,param)#`
+func (p *parser) tag() exec.Operation {
 	// Consume opening #
-	s.Next()
-	key := s.Next().Value
-	var mods []exec.ModCall
+	p.s.Next()
 
-	var ruleParts []string
-	for {
-		rawValue := s.Next()
-		if rawValue.Type == scan.Octo {
-			break
-		}
-		if rawValue.Type == scan.Period {
-			mod := parseModifier(s)
-			mods = append(mods, mod)
-			continue
-		}
+	// Actions before the key only last while the symbol expands
+	var actions []exec.Operation
+	for p.s.Peek().Type == scan.LeftBracket {
+		actions = append(actions, p.action())
+	}
+
+	key := p.text(scan.Period, scan.Octo)
 
-		ruleParts = append(ruleParts, rawValue.Value)
+	var mods []exec.ModCall
+	for p.s.Peek().Type == scan.Period {
+		// Consume .
+		p.s.Next()
+		mods = append(mods, p.modifier())
 	}
+	p.expect(scan.Octo)
 
+	if len(actions) > 0 {
+		return exec.NewSymbolWithActions(actions, key, mods)
+	}
 	return exec.NewSymbolWithMods(key, mods)
 }
 
-func parseModifier(s *scan.Scanner) exec.ModCall {
-	key := s.Next().Value
+// modifier reads `mod` or `mod(param,param)` after the period
+func (p *parser) modifier() exec.ModCall {
+	key := p.text(scan.LeftParen, scan.Period, scan.Octo)
 
-	if s.Peek().Type != scan.LeftParen {
+	if p.s.Peek().Type != scan.LeftParen {
 		return exec.NewModCallZero(key)
 	}
 	// Consume (
-	s.Next()
+	p.s.Next()
 
 	var ops []exec.Operation
-
-	var ruleParts []string
 	for {
-		rawValue := s.Next()
-		if rawValue.Type == scan.RightParen {
-			op := String(strings.Join(ruleParts, ""))
-			ops = append(ops, op)
+		ops = append(ops, p.rule(modParam))
+		if p.s.Peek().Type != scan.Comma {
 			break
 		}
-		if rawValue.Type == scan.Comma {
-			op := String(strings.Join(ruleParts, ""))
-			ops = append(ops, op)
-			ruleParts = ruleParts[:0]
-			continue
-		}
-
-		ruleParts = append(ruleParts, rawValue.Value)
+		// Consume ,
+		p.s.Next()
 	}
+	p.expect(scan.RightParen)
 
 	return exec.NewModCall(key, ops)
 }
 
-func parseLiteral(s *scan.Scanner) exec.Operation {
-	var texts []string
-
-	var token scan.Token
-Loop:
+// text reads plain text, e.g. a key, up to any of the given token types
+func (p *parser) text(until ...scan.Type) string {
+	var text strings.Builder
 	for {
-		token = s.Peek()
-		switch token.Type {
-		case scan.LeftBracket:
-			fallthrough
-		case scan.Octo:
-			fallthrough
-		case scan.EOF:
-			fallthrough
-		case scan.Error:
-			break Loop
-		default:
-			texts = append(texts, s.Next().Value)
+		token := p.s.Peek()
+		if token.Type == scan.EOF || token.Type == scan.Error {
+			return text.String()
+		}
+		for _, t := range until {
+			if token.Type == t {
+				return text.String()
+			}
 		}
+		text.WriteString(p.s.Next().Value)
+	}
+}
+
+// expect consumes the closing token of a tag, action or param list. It's
+// missing at the end of the input, which closes everything left open
+func (p *parser) expect(t scan.Type) {
+	// @incomplete: report a parse error when anything else is found
+	if p.s.Peek().Type == t {
+		p.s.Next()
 	}
-	value := strings.Join(texts, "")
-	return exec.NewLiteral(value)
 }
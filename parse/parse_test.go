@@ -71,6 +71,8 @@ func TestParseSymbols(t *testing.T) {
 			{"#sym.mod.mod.mod#", exec.NewSymbolWithMods("sym", []exec.ModCall{exec.NewModCallZero("mod"), exec.NewModCallZero("mod"), exec.NewModCallZero("mod")})},
 			{"#sym.mod(param)#", exec.NewSymbolWithMods("sym", []exec.ModCall{exec.NewModCall("mod", []exec.Operation{exec.NewLiteral("param")})})},
 			{"#sym.mod(par,am)#", exec.NewSymbolWithMods("sym", []exec.ModCall{exec.NewModCall("mod", []exec.Operation{exec.NewLiteral("par"), exec.NewLiteral("am")})})},
+			{"#[act:lit]sym#", exec.NewSymbolWithActions([]exec.Operation{exec.NewPush("act", exec.NewLiteral("lit"))}, "sym", nil)},
+			{"#[act:lit][b:#c#]sym.mod#", exec.NewSymbolWithActions([]exec.Operation{exec.NewPush("act", exec.NewLiteral("lit")), exec.NewPush("b", exec.NewSymbol("c"))}, "sym", []exec.ModCall{exec.NewModCallZero("mod")})},
 		}
 
 		for _, tt := range tests {
@@ -119,6 +121,9 @@ func TestParseActions(t *testing.T) {
 			{"[act:lit,lit]", exec.NewPush("act", exec.NewSelect([]exec.Operation{exec.NewLiteral("lit"), exec.NewLiteral("lit")}))},
 			{`[act:lit\,eral]`, exec.NewPush("act", exec.NewLiteral("lit,eral"))},
 			{"[act:POP]", exec.NewPop("act")},
+			{"[act:CLEAR]", exec.NewClear("act")},
+			{"[#sym#]", exec.NewCall(exec.NewSymbol("sym"))},
+			{"[#[act:lit]sym.mod#]", exec.NewCall(exec.NewSymbolWithActions([]exec.Operation{exec.NewPush("act", exec.NewLiteral("lit"))}, "sym", []exec.ModCall{exec.NewModCallZero("mod")}))},
 			// @question: Can POP be escaped?
 			// {"[act:\POP]", PushOp{key: "act", value: LiteralValue{value: "POP"}}},
 		}
@@ -154,6 +159,59 @@ func TestParseActions(t *testing.T) {
 	})
 }
 
+func TestParseNesting(t *testing.T) {
+	lit := exec.NewLiteral
+	ops := func(ops ...exec.Operation) []exec.Operation { return ops }
+	var tests = []struct {
+		input    string
+		expected exec.Operation
+	}{
+		{"[a:[b:x]#b#]", exec.NewPush("a", exec.NewConcat(ops(exec.NewPush("b", lit("x")), exec.NewSymbol("b"))))},
+		{"[a:[b:x,y],z]", exec.NewPush("a", exec.NewSelect(ops(exec.NewPush("b", exec.NewSelect(ops(lit("x"), lit("y")))), lit("z"))))},
+		{"[a:[b:[c:x]]]", exec.NewPush("a", exec.NewPush("b", exec.NewPush("c", lit("x"))))},
+		{"[a:#[b:x]c#]", exec.NewPush("a", exec.NewSymbolWithActions(ops(exec.NewPush("b", lit("x"))), "c", nil))},
+		{`[a:\#b\#]`, exec.NewPush("a", lit("#b#"))},
+		{`[a:\[b:c\]]`, exec.NewPush("a", lit("[b:c]"))},
+		{`[a:x\,y,z]`, exec.NewPush("a", exec.NewSelect(ops(lit("x,y"), lit("z"))))},
+		{`[a:\\]`, exec.NewPush("a", lit(`\`))},
+		{"[a:(x) y.z]", exec.NewPush("a", lit("(x) y.z"))},
+		{"#a.b(c (d))#", exec.NewSymbolWithMods("a", []exec.ModCall{exec.NewModCall("b", ops(lit("c (d)")))})},
+		{"#a.b((c,d),e)#", exec.NewSymbolWithMods("a", []exec.ModCall{exec.NewModCall("b", ops(lit("(c,d)"), lit("e")))})},
+		{`#a.b(c\,d,e\))#`, exec.NewSymbolWithMods("a", []exec.ModCall{exec.NewModCall("b", ops(lit("c,d"), lit("e)")))})},
+		{`#a.b(\#c\#)#`, exec.NewSymbolWithMods("a", []exec.ModCall{exec.NewModCall("b", ops(lit("#c#")))})},
+		{"#a.b([c:d]#c#)#", exec.NewSymbolWithMods("a", []exec.ModCall{exec.NewModCall("b", ops(exec.NewConcat(ops(exec.NewPush("c", lit("d")), exec.NewSymbol("c")))))})},
+		{"#a.b(#c.d(e)#)#", exec.NewSymbolWithMods("a", []exec.ModCall{exec.NewModCall("b", ops(exec.NewSymbolWithMods("c", []exec.ModCall{exec.NewModCall("d", ops(lit("e")))})))})},
+		{"a) b] c, d: e.", lit("a) b] c, d: e.")},
+		{"{svg <path d='M0,0'/>}", exec.NewBlock(lit("svg <path d='M0,0'/>"))},
+		{"{img #url#}", exec.NewBlock(exec.NewConcat(ops(lit("img "), exec.NewSymbol("url"))))},
+		{"[a:{x,y},z]", exec.NewPush("a", exec.NewSelect(ops(exec.NewBlock(lit("x,y")), lit("z"))))},
+		{"#a.b({c,(d)})#", exec.NewSymbolWithMods("a", []exec.ModCall{exec.NewModCall("b", ops(exec.NewBlock(lit("c,(d)"))))})},
+		// A brace with no } before the end of its action or param is text
+		{"[a:b{]#a# #c#", exec.NewConcat(ops(exec.NewPush("a", lit("b{")), exec.NewSymbol("a"), lit(" "), exec.NewSymbol("c")))},
+		{"#a.b(c{)# #c#", exec.NewConcat(ops(exec.NewSymbolWithMods("a", []exec.ModCall{exec.NewModCall("b", ops(lit("c{")))}), lit(" "), exec.NewSymbol("c")))},
+		{"[a:{b #c#]", exec.NewPush("a", exec.NewConcat(ops(lit("{b "), exec.NewSymbol("c"))))},
+		{"[x:{a,b]#x#", exec.NewConcat(ops(exec.NewPush("x", exec.NewSelect(ops(lit("{a"), lit("b")))), exec.NewSymbol("x")))},
+		{"#m.f({a,b)#", exec.NewSymbolWithMods("m", []exec.ModCall{exec.NewModCall("f", ops(lit("{a"), lit("b")))})},
+		{"[a:{b{c}]}", exec.NewConcat(ops(exec.NewPush("a", exec.NewConcat(ops(lit("{b"), exec.NewBlock(lit("c"))))), lit("}")))},
+		{"{a {b} c}", exec.NewBlock(exec.NewConcat(ops(lit("a "), exec.NewBlock(lit("b")), lit(" c"))))},
+		{"a } b", lit("a } b")},
+		{`\{a\}`, lit("{a}")},
+		// Unclosed input is closed by the end of the input
+		{"#a", exec.NewSymbol("a")},
+		{"[a:b", exec.NewPush("a", lit("b"))},
+		{"[a:[b:c", exec.NewPush("a", exec.NewPush("b", lit("c")))},
+		{"#a.b(c", exec.NewSymbolWithMods("a", []exec.ModCall{exec.NewModCall("b", ops(lit("c")))})},
+		{"{a", lit("{a")},
+	}
+
+	for _, tt := range tests {
+		actual := String(tt.input)
+		if !testRuleEq(actual, tt.expected) {
+			t.Errorf("String(%v): expected %v, actual %v", tt.input, tt.expected, actual)
+		}
+	}
+}
+
 func TestParseMultiple(t *testing.T) {
 	t.Run("valid inputs", func(t *testing.T) {
 		var tests = []struct {
@@ -214,3 +272,36 @@ func TestParseMultiple(t *testing.T) {
 // nesting
 
 // */
+
+func TestSource(t *testing.T) {
+	var tests = []string{
+		"",
+		"a",
+		"A complete sentence, oh my.",
+		`\#sym\# \[key:literal]`,
+		`\\`,
+		"#sym#",
+		"#sym.mod.mod#",
+		"#sym.mod(par,am)#",
+		"#sym.mod(#x#)#",
+		"[act:lit]",
+		"[act:lit,#sym#]",
+		"[act:POP]",
+		"[act:CLEAR]",
+		"#[act:lit][b:#c#]sym.mod#",
+		"[#sym#]#[#sym#]sym#",
+		`[a:[b:\#x\,y\]],z]`,
+		`#a.b(c\,d,e\),[f:g])#`,
+		"The #pace# #animal#[animal:POP] #animal#",
+		"{svg <g>#shape#</g>}",
+		"[a:{b,c},d]",
+		`\{a} {b\}c}`,
+	}
+
+	for _, input := range tests {
+		actual := exec.Source(String(input))
+		if actual != input {
+			t.Errorf("String(%v).Source(): expected %v, actual %v", input, input, actual)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package tracery
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/martletandco/tracery-go/exec"
+)
+
+// Pin makes a symbol always pick one of its options, leaving every other
+// choice random, e.g. to hold part of an output steady while tuning the rest.
+// The option is either its rule, as given by Rules, or its index. The pin
+// lasts until Unpin, but only applies while the symbol still has that option
+func (g *Grammar) Pin(key string, option string) error {
+	rules := g.Rules(key)
+	if rules == nil {
+		return fmt.Errorf("can't pin %q: no such symbol", key)
+	}
+
+	for _, rule := range rules {
+		if rule == option {
+			g.own()
+			g.pins[key] = rule
+			return nil
+		}
+	}
+	if i, err := strconv.Atoi(option); err == nil && i >= 0 && i < len(rules) {
+		g.own()
+		g.pins[key] = rules[i]
+		return nil
+	}
+	return fmt.Errorf("can't pin %q: no option %q in %q", key, option, rules)
+}
+
+// Unpin lets a pinned symbol pick any option again
+func (g *Grammar) Unpin(key string) {
+	g.own()
+	delete(g.pins, key)
+}
+
+// pinned gives the index of the symbol's pinned option, if it's pinned to one
+// of the options
+func (g *Grammar) pinned(key string, options []exec.Operation) (int, bool) {
+	pin, ok := g.pins[key]
+	if !ok {
+		return 0, false
+	}
+	for i, option := range options {
+		if exec.Source(option) == pin {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package tracery
+
+import "testing"
+
+func TestPin(t *testing.T) {
+	pinGrammar := func() Grammar {
+		g := NewGrammar()
+		g.PushRule("size", "big", "small", "tiny")
+		g.PushRule("animal", "fox", "dog", "#size# emu")
+		return g
+	}
+
+	t.Run("it always picks the pinned option", func(t *testing.T) {
+		for _, option := range []string{"dog", "1"} {
+			g := pinGrammar()
+			if err := g.Pin("animal", option); err != nil {
+				t.Fatalf("Pin(%v): encountered error: %v", option, err)
+			}
+			for i := 0; i < 20; i++ {
+				if got := g.Flatten("#animal#"); got != "dog" {
+					t.Errorf("Pin(%v): got '%s' want 'dog'", option, got)
+				}
+			}
+		}
+	})
+	t.Run("it leaves other choices random", func(t *testing.T) {
+		g := pinGrammar()
+		g.Pin("animal", "#size# emu")
+		g.Rand = func(n int) int { return n - 1 }
+		got := g.Flatten("#animal#")
+		want := "tiny emu"
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it wins over a chooser, until unpinned", func(t *testing.T) {
+		g := pinGrammar()
+		g.Chooser = FirstChooser{}
+		g.Pin("size", "small")
+		if got, want := g.Flatten("#size#"), "small"; got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		g.Unpin("size")
+		if got, want := g.Flatten("#size#"), "big"; got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it doesn't pin clones", func(t *testing.T) {
+		g := pinGrammar()
+		c := g.Clone()
+		c.Pin("size", "small")
+		c.Chooser, g.Chooser = FirstChooser{}, FirstChooser{}
+		if got, want := g.Flatten("#size#"), "big"; got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it refuses options which don't exist", func(t *testing.T) {
+		var tests = [][2]string{
+			{"animal", "cat"},
+			{"animal", "3"},
+			{"animal", "-1"},
+			{"missing", "fox"},
+		}
+		for _, tt := range tests {
+			g := pinGrammar()
+			if err := g.Pin(tt[0], tt[1]); err == nil {
+				t.Errorf("Pin(%v, %v): expected an error", tt[0], tt[1])
+			}
+		}
+	})
+}
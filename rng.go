@@ -0,0 +1,117 @@
+package tracery
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"math/bits"
+)
+
+// RNG is a source of random numbers for a Grammar, see SetRNG
+type RNG interface {
+	// Intn gives a number in [0,n), as rand.Intn
+	Intn(n int) int
+}
+
+// SetRNG makes the grammar pick options with the RNG, which takes precedence
+// over Rand until SetRNG(nil) goes back to Rand. NewGrammar uses math/rand,
+// whose sequences may change between Go versions, so use a PCG for outputs
+// which must stay the same, or a CryptoRNG for outputs which mustn't be
+// predictable
+func (g *Grammar) SetRNG(r RNG) {
+	g.rng = r
+}
+
+// RNG gives the RNG set by SetRNG, or nil if options are picked with Rand
+func (g *Grammar) RNG() RNG {
+	return g.rng
+}
+
+// PCGVersion is the version of the PCG sequence. A PCG seeded the same way
+// gives the same numbers for as long as the version stays the same, which is
+// meant to be forever
+const PCGVersion = 1
+
+// PCG is the PCG32 generator (XSH RR 64/32) as specified at
+// https://www.pcg-random.org, with outputs matching the reference pcg32_random_r.
+// Intn takes two outputs, the first as the high 32 bits, and scales them to
+// [0,n) with Lemire's multiply and reject method
+type PCG struct {
+	state uint64
+	inc   uint64
+}
+
+const pcgMultiplier = 6364136223846793005
+
+// NewPCG seeds a PCG as the reference pcg32_srandom_r(seed, seq) does. The
+// seq picks one of 2^63 separate streams
+func NewPCG(seed, seq uint64) *PCG {
+	p := &PCG{inc: seq<<1 | 1}
+	p.Uint32()
+	p.state += seed
+	p.Uint32()
+	return p
+}
+
+// Uint32 gives the next output of the generator
+func (p *PCG) Uint32() uint32 {
+	old := p.state
+	p.state = old*pcgMultiplier + p.inc
+	xorshifted := uint32(((old >> 18) ^ old) >> 27)
+	rot := uint(old >> 59)
+	return bits.RotateLeft32(xorshifted, -int(rot))
+}
+
+func (p *PCG) uint64() uint64 {
+	return uint64(p.Uint32())<<32 | uint64(p.Uint32())
+}
+
+func (p *PCG) Intn(n int) int {
+	if n <= 0 {
+		panic("invalid argument to Intn")
+	}
+	bound := uint64(n)
+	hi, lo := bits.Mul64(p.uint64(), bound)
+	if lo < bound {
+		threshold := -bound % bound
+		for lo < threshold {
+			hi, lo = bits.Mul64(p.uint64(), bound)
+		}
+	}
+	return int(hi)
+}
+
+// MarshalBinary saves the position of the generator
+func (p *PCG) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 17)
+	b[0] = PCGVersion
+	binary.BigEndian.PutUint64(b[1:], p.state)
+	binary.BigEndian.PutUint64(b[9:], p.inc)
+	return b, nil
+}
+
+// UnmarshalBinary restores a position saved by MarshalBinary
+func (p *PCG) UnmarshalBinary(b []byte) error {
+	if len(b) != 17 || b[0] != PCGVersion {
+		return errors.New("pcg: unknown state format")
+	}
+	p.state = binary.BigEndian.Uint64(b[1:])
+	p.inc = binary.BigEndian.Uint64(b[9:])
+	return nil
+}
+
+// CryptoRNG gives unpredictable numbers from crypto/rand. It has no seed or
+// state, so its outputs can't be repeated
+type CryptoRNG struct{}
+
+func (CryptoRNG) Intn(n int) int {
+	if n <= 0 {
+		panic("invalid argument to Intn")
+	}
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(err)
+	}
+	return int(i.Int64())
+}
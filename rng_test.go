@@ -0,0 +1,92 @@
+package tracery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPCG(t *testing.T) {
+	t.Run("it matches the reference implementation", func(t *testing.T) {
+		// From pcg32-demo in the reference C implementation
+		p := NewPCG(42, 54)
+		var got []uint32
+		for i := 0; i < 6; i++ {
+			got = append(got, p.Uint32())
+		}
+		want := []uint32{0xa15c02b7, 0x7b47f409, 0xba1d3330, 0x83d2f293, 0xbfa4784b, 0xcbed606e}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got '%x' want '%x'", got, want)
+		}
+	})
+	t.Run("it keeps giving the same numbers across releases", func(t *testing.T) {
+		p := NewPCG(1, 0)
+		var got []int
+		for i := 0; i < 10; i++ {
+			got = append(got, p.Intn(10))
+		}
+		want := []int{8, 8, 5, 6, 8, 3, 6, 0, 0, 6}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+	})
+	t.Run("it carries on from a saved position", func(t *testing.T) {
+		p := NewPCG(7, 3)
+		p.Intn(100)
+		state, _ := p.MarshalBinary()
+		want := p.Intn(1000)
+
+		var restored PCG
+		if err := restored.UnmarshalBinary(state); err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		if got := restored.Intn(1000); got != want {
+			t.Errorf("got %d want %d", got, want)
+		}
+		if err := restored.UnmarshalBinary(state[1:]); err == nil {
+			t.Errorf("expected an error for a short state")
+		}
+	})
+}
+
+func TestSetRNG(t *testing.T) {
+	t.Run("it picks options with the RNG", func(t *testing.T) {
+		flatten := func() string {
+			g := NewGrammar()
+			g.SetRNG(NewPCG(3, 0))
+			g.PushRule("animal", "fox", "dog", "emu", "cow", "owl", "yak")
+			return g.Flatten("#animal# #animal# #animal# #animal#")
+		}
+		if got, want := flatten(), flatten(); got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it takes precedence over Rand", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("animal", "fox", "dog", "emu")
+		g.SetRNG(NewPCG(3, 0))
+		g.Rand = func(n int) int { return 2 }
+		want := NewPCG(3, 0).Intn(3)
+		if got := g.Intn(3); got != want {
+			t.Errorf("got %d want %d", got, want)
+		}
+		g.SetRNG(nil)
+		if got := g.Intn(3); got != 2 {
+			t.Errorf("got %d want %d from Rand", got, 2)
+		}
+	})
+	t.Run("it picks unpredictable options with crypto/rand", func(t *testing.T) {
+		g := NewGrammar()
+		g.SetRNG(CryptoRNG{})
+		g.PushRule("animal", "fox", "dog")
+		seen := map[string]bool{}
+		for i := 0; i < 100; i++ {
+			seen[g.Flatten("#animal#")] = true
+		}
+		if len(seen) != 2 {
+			t.Errorf("got '%v' want both options", seen)
+		}
+		if _, ok := g.RNG().(CryptoRNG); !ok {
+			t.Errorf("got '%v' want the CryptoRNG", g.RNG())
+		}
+	})
+}
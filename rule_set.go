@@ -1,11 +1,135 @@
 package tracery
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 type RuleSet map[string]Rule
 
+// Keys lists the symbols of the set in sorted order, as map order is random
+func (set RuleSet) Keys() []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Handles `{"a": "rule", "b": ["rule", "rule"]}`. As with OrderedRuleSet keys
+// starting with $ aren't symbols, e.g. `"$tests"`, and are skipped
+func (set *RuleSet) UnmarshalJSON(b []byte) error {
+	var ordered OrderedRuleSet
+	if err := ordered.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	if ordered != nil {
+		*set = ordered.RuleSet()
+	}
+	return nil
+}
+
+// OrderedRuleSet is a RuleSet which keeps its symbols in the order they were
+// defined in, so that loading, tracing and exporting are repeatable
+type OrderedRuleSet []RuleSetEntry
+
+type RuleSetEntry struct {
+	Key  string
+	Rule Rule
+}
+
+// Set replaces the rule of an existing symbol in place, or adds it at the end
+func (set *OrderedRuleSet) Set(key string, rule Rule) {
+	for i := range *set {
+		if (*set)[i].Key == key {
+			(*set)[i].Rule = rule
+			return
+		}
+	}
+	*set = append(*set, RuleSetEntry{Key: key, Rule: rule})
+}
+
+// RuleSet returns the set without its ordering
+func (set OrderedRuleSet) RuleSet() RuleSet {
+	out := make(RuleSet, len(set))
+	for _, entry := range set {
+		out[entry.Key] = entry.Rule
+	}
+	return out
+}
+
+// Handles `{"b": "rule", "a": ["rule", "rule"]}` keeping b before a. As with a
+// map a repeated key replaces the earlier rule, but keeps its place. Keys
+// starting with $ aren't symbols, e.g. `"$tests"`, and are skipped
+func (set *OrderedRuleSet) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("expected a rule set object, found %v", tok)
+	}
+
+	out := OrderedRuleSet{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(tok.(string), "$") {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		var rule Rule
+		if err := dec.Decode(&rule); err != nil {
+			return err
+		}
+		out.Set(tok.(string), rule)
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	*set = out
+	return nil
+}
+
+// MarshalJSON writes the set as an object with its keys in order
+func (set OrderedRuleSet) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, entry := range set {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		rule, err := json.Marshal([]string(entry.Rule))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(rule)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
 type Rule []string
 
 // Handles `"rule"` and `["rule", "rule"]`
@@ -22,3 +146,34 @@ func (rs *Rule) UnmarshalJSON(b []byte) error {
 	*rs = []string{s}
 	return nil
 }
+
+// formats maps file extensions to the grammar format they hold
+var formats = map[string]string{
+	".json": "json",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".toml": "toml",
+}
+
+// FormatOf returns the grammar format of a file, based on its extension
+func FormatOf(name string) (string, bool) {
+	format, ok := formats[strings.ToLower(filepath.Ext(name))]
+	return format, ok
+}
+
+// ParseRuleSet reads a RuleSet in the given format: "json", "yaml" or "toml"
+func ParseRuleSet(format string, data []byte) (OrderedRuleSet, error) {
+	switch format {
+	case "json":
+		var set OrderedRuleSet
+		if err := json.Unmarshal(data, &set); err != nil {
+			return nil, err
+		}
+		return set, nil
+	case "yaml":
+		return ParseYAML(data)
+	case "toml":
+		return ParseTOML(data)
+	}
+	return nil, fmt.Errorf("unknown grammar format %q", format)
+}
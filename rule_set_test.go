@@ -2,6 +2,7 @@ package tracery
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -18,6 +19,7 @@ func TestRuleSetUnmarshal(t *testing.T) {
 			{`{"x": "a", "y": "b"}`, RuleSet{"x": Rule{"a"}, "y": Rule{"b"}}},
 			{`{"x": ["a"], "y": "b"}`, RuleSet{"x": Rule{"a"}, "y": Rule{"b"}}},
 			{`{"x": ["a"], "y": ["b"]}`, RuleSet{"x": Rule{"a"}, "y": Rule{"b"}}},
+			{`{"x": "a", "$tests": [{"expr": "#x#", "seed": 1, "expected": "a"}]}`, RuleSet{"x": Rule{"a"}}},
 		}
 
 		for _, tt := range tests {
@@ -55,3 +57,56 @@ func ruleSetEqual(a, b RuleSet) bool {
 
 	return true
 }
+
+func TestOrderedRuleSet(t *testing.T) {
+	t.Run("it keeps keys in source order", func(t *testing.T) {
+		var set OrderedRuleSet
+		input := `{"z": "a", "b": ["b", "c"], "m": [], "b": "d"}`
+		if err := json.Unmarshal([]byte(input), &set); err != nil {
+			t.Fatalf("String(%v): encountered error: %v", input, err)
+		}
+		var keys []string
+		for _, entry := range set {
+			keys = append(keys, entry.Key)
+		}
+		if got, want := strings.Join(keys, ","), "z,b,m"; got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		if ruleSetEqual(set.RuleSet(), RuleSet{"z": Rule{"a"}, "b": Rule{"d"}, "m": Rule{}}) == false {
+			t.Errorf("got '%v'", set)
+		}
+	})
+	t.Run("it skips keys starting with $", func(t *testing.T) {
+		var set OrderedRuleSet
+		input := `{"$tests": [{"expr": "#a#"}], "a": "b"}`
+		if err := json.Unmarshal([]byte(input), &set); err != nil {
+			t.Fatalf("String(%v): encountered error: %v", input, err)
+		}
+		if ruleSetEqual(set.RuleSet(), RuleSet{"a": Rule{"b"}}) == false {
+			t.Errorf("got '%v'", set)
+		}
+	})
+	t.Run("it writes keys in order", func(t *testing.T) {
+		set := OrderedRuleSet{{"z", Rule{"a"}}, {"b", Rule{"b", "c"}}}
+		out, err := json.Marshal(set)
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		if got, want := string(out), `{"z":["a"],"b":["b","c"]}`; got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it rejects values which aren't objects", func(t *testing.T) {
+		var set OrderedRuleSet
+		if err := json.Unmarshal([]byte(`["a"]`), &set); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestRuleSetKeys(t *testing.T) {
+	set := RuleSet{"c": Rule{}, "a": Rule{}, "b": Rule{}}
+	if got, want := strings.Join(set.Keys(), ","), "a,b,c"; got != want {
+		t.Errorf("got '%s' want '%s'", got, want)
+	}
+}
@@ -0,0 +1,273 @@
+package tracery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseTOML reads a RuleSet from a TOML document. Only the subset of TOML
+// needed to describe a rule set is supported: top level `key = value` pairs
+// whose values are strings or arrays of strings, i.e. the same shapes
+// Rule.UnmarshalJSON handles. All four string styles are supported, including
+// multi-line basic and literal strings, in triple double or single quotes.
+//
+// A TOML grammar can't carry a $tests section, as its cases are tables, so
+// golden tests for it go in a JSON grammar or a separate cases file
+func ParseTOML(data []byte) (OrderedRuleSet, error) {
+	p := tomlParser{input: strings.ReplaceAll(string(data), "\r\n", "\n"), line: 1}
+	return p.parse()
+}
+
+type tomlParser struct {
+	input string
+	pos   int
+	line  int
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) parse() (OrderedRuleSet, error) {
+	set := OrderedRuleSet{}
+	seen := map[string]bool{}
+	for {
+		p.skip(true)
+		if p.eof() {
+			return set, nil
+		}
+		if p.peek() == '[' {
+			return nil, p.errorf("tables are not supported in rule sets")
+		}
+
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+
+		p.skip(false)
+		if p.peek() != '=' {
+			return nil, p.errorf("expected '=' after key %q", key)
+		}
+		p.pos++
+		p.skip(false)
+
+		var rule Rule
+		if p.peek() == '[' {
+			rule, err = p.array()
+		} else {
+			var s string
+			s, err = p.str()
+			rule = Rule{s}
+		}
+		if err != nil {
+			return nil, err
+		}
+		seen[key] = true
+		set = append(set, RuleSetEntry{Key: key, Rule: rule})
+
+		// Only a comment may follow a value on the same line
+		p.skip(false)
+		if !p.eof() && p.peek() != '\n' {
+			return nil, p.errorf("unexpected text after value of %q", key)
+		}
+	}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// skip passes over whitespace and comments, and newlines when asked to
+func (p *tomlParser) skip(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '\n' && newlines:
+			p.pos++
+			p.line++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) key() (string, error) {
+	if c := p.peek(); c == '"' || c == '\'' {
+		return p.str()
+	}
+
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			break
+		}
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("expected a key")
+	}
+	if p.peek() == '.' {
+		return "", p.errorf("dotted keys are not supported in rule sets")
+	}
+	return p.input[start:p.pos], nil
+}
+
+func (p *tomlParser) array() (Rule, error) {
+	// Consume [
+	p.pos++
+	rule := Rule{}
+	for {
+		p.skip(true)
+		if p.peek() == ']' {
+			p.pos++
+			return rule, nil
+		}
+
+		s, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		rule = append(rule, s)
+
+		p.skip(true)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) str() (string, error) {
+	rest := p.input[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return p.multiline(`"""`, true)
+	case strings.HasPrefix(rest, `'''`):
+		return p.multiline(`'''`, false)
+	case strings.HasPrefix(rest, `"`):
+		end := 1
+		for ; end < len(rest) && rest[end] != '"' && rest[end] != '\n'; end++ {
+			if rest[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(rest) || rest[end] != '"' {
+			return "", p.errorf("unterminated string")
+		}
+		p.pos += end + 1
+		return p.unescape(rest[1:end])
+	case strings.HasPrefix(rest, `'`):
+		end := strings.IndexAny(rest[1:], "'\n") + 1
+		if end == 0 || rest[end] != '\'' {
+			return "", p.errorf("unterminated string")
+		}
+		p.pos += end + 1
+		return rest[1:end], nil
+	}
+	return "", p.errorf("expected a string")
+}
+
+// multiline reads a string in triple double or single quotes, where a newline
+// straight after the opening quotes is trimmed
+func (p *tomlParser) multiline(quote string, escapes bool) (string, error) {
+	p.pos += len(quote)
+	if p.peek() == '\n' {
+		p.pos++
+		p.line++
+	}
+
+	rest := p.input[p.pos:]
+	end := strings.Index(rest, quote)
+	if end < 0 {
+		return "", p.errorf("unterminated multi-line string")
+	}
+	// Up to two quotes are allowed right before the closing ones
+	for i := 0; i < 2 && end+len(quote) < len(rest) && rest[end+len(quote)] == quote[0]; i++ {
+		end++
+	}
+
+	raw := rest[:end]
+	p.pos += end + len(quote)
+	p.line += strings.Count(raw, "\n")
+
+	if !escapes {
+		return raw, nil
+	}
+	return p.unescape(raw)
+}
+
+// unescape handles the escapes of basic strings, including the line ending
+// backslash of multi-line strings which trims the following whitespace
+func (p *tomlParser) unescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", p.errorf("invalid escape at end of string")
+		}
+		switch c := s[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(c)
+		case 'u', 'U':
+			size := 4
+			if c == 'U' {
+				size = 8
+			}
+			if i+size >= len(s) {
+				return "", p.errorf("invalid unicode escape")
+			}
+			n, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(n)) {
+				return "", p.errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(n))
+			i += size
+		case ' ', '\t', '\n':
+			rest := s[i:]
+			trimmed := strings.TrimLeft(rest, " \t\n")
+			if !strings.Contains(rest[:len(rest)-len(trimmed)], "\n") {
+				return "", p.errorf("invalid escape")
+			}
+			i += len(rest) - len(trimmed) - 1
+		default:
+			return "", p.errorf("invalid escape '\\%c'", c)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,56 @@
+package tracery
+
+import "testing"
+
+func TestParseTOML(t *testing.T) {
+	t.Run("valid inputs", func(t *testing.T) {
+		var tests = []struct {
+			input    string
+			expected RuleSet
+		}{
+			{``, RuleSet{}},
+			{`x = "a"`, RuleSet{"x": Rule{"a"}}},
+			{"x = 'a' # comment\n# comment\ny = \"b\"", RuleSet{"x": Rule{"a"}, "y": Rule{"b"}}},
+			{`x = "#a# \"b\" \u00e9"`, RuleSet{"x": Rule{`#a# "b" é`}}},
+			{`x = '#a# "b" \n'`, RuleSet{"x": Rule{`#a# "b" \n`}}},
+			{`"x y" = "a"`, RuleSet{"x y": Rule{"a"}}},
+			{`x = ["a", '#b#']`, RuleSet{"x": Rule{"a", "#b#"}}},
+			{"x = [\n  \"a\", # first\n  \"b\",\n]", RuleSet{"x": Rule{"a", "b"}}},
+			{`x = []`, RuleSet{"x": Rule{}}},
+			{"x = \"\"\"\n#a#\n  \"b\"\n\"\"\"", RuleSet{"x": Rule{"#a#\n  \"b\"\n"}}},
+			{"x = \"\"\"a \\\n    b\"\"\"", RuleSet{"x": Rule{"a b"}}},
+			{"x = '''\n#a# \\n\n'''", RuleSet{"x": Rule{"#a# \\n\n"}}},
+			{`x = """a"""""`, RuleSet{"x": Rule{`a""`}}},
+		}
+
+		for _, tt := range tests {
+			set, err := ParseTOML([]byte(tt.input))
+			if err != nil {
+				t.Errorf("ParseTOML(%q): encountered error: %v", tt.input, err)
+			}
+			if ruleSetEqual(set.RuleSet(), tt.expected) == false {
+				t.Errorf("ParseTOML(%q): expected '%q', got '%q'", tt.input, tt.expected, set)
+			}
+		}
+	})
+	t.Run("inputs which should error", func(t *testing.T) {
+		var tests = []string{
+			`x`,
+			`x = a`,
+			`x = 1`,
+			"x = \"a\"\nx = \"b\"",
+			`x = "a" "b"`,
+			`x = "a`,
+			`x = ["a" "b"]`,
+			`x.y = "a"`,
+			"[table]\nx = \"a\"",
+			`x = "\q"`,
+		}
+
+		for _, input := range tests {
+			if _, err := ParseTOML([]byte(input)); err == nil {
+				t.Errorf("ParseTOML(%q): expected an error", input)
+			}
+		}
+	})
+}
@@ -0,0 +1,353 @@
+package tracery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseYAML reads a RuleSet from a YAML document. Only the subset of YAML
+// needed to describe a rule set is supported: a single top level mapping whose
+// values are scalars or lists of scalars, i.e. the same shapes Rule.UnmarshalJSON
+// handles. Scalars can be plain, quoted, or literal (|) and folded (>) blocks.
+//
+// Note that, as in any YAML, a plain value can't start with '#' and ' #' starts
+// a comment, so rules using symbols are best quoted or written as blocks.
+//
+// A YAML grammar can't carry a $tests section, as its cases are mappings, so
+// golden tests for it go in a JSON grammar or a separate cases file
+func ParseYAML(data []byte) (OrderedRuleSet, error) {
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	p := yamlParser{lines: strings.Split(text, "\n")}
+	return p.parse()
+}
+
+type yamlParser struct {
+	lines []string
+	n     int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", p.n+1, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) parse() (OrderedRuleSet, error) {
+	set := OrderedRuleSet{}
+	seen := map[string]bool{}
+	for ; p.n < len(p.lines); p.n++ {
+		line := p.lines[p.n]
+		if yamlBlank(line) || line == "---" || line == "..." {
+			continue
+		}
+		if yamlIndent(line) > 0 {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		key, rest, err := p.splitKey(line)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+
+		rule, err := p.value(rest)
+		if err != nil {
+			return nil, err
+		}
+		seen[key] = true
+		set = append(set, RuleSetEntry{Key: key, Rule: rule})
+	}
+	return set, nil
+}
+
+// splitKey separates `key: rest`, where key may be quoted
+func (p *yamlParser) splitKey(line string) (string, string, error) {
+	if line[0] == '"' || line[0] == '\'' {
+		key, rest, err := p.quoted(line)
+		if err != nil {
+			return "", "", err
+		}
+		rest = strings.TrimLeft(rest, " \t")
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", p.errorf("expected ':' after key")
+		}
+		return key, rest[1:], nil
+	}
+
+	i := strings.Index(line+" ", ": ")
+	if i <= 0 {
+		return "", "", p.errorf("expected 'key: value'")
+	}
+	return strings.TrimSpace(line[:i]), line[i+1:], nil
+}
+
+// value reads the value of a top level key, which is either on the rest of
+// the key's line or in the indented lines following it
+func (p *yamlParser) value(rest string) (Rule, error) {
+	// A flow list strips its own comment, as its items may be quoted
+	if trimmed := strings.TrimSpace(rest); strings.HasPrefix(trimmed, "[") {
+		return p.flowSequence(trimmed)
+	}
+	rest = strings.TrimSpace(yamlStripComment(rest))
+	if rest == "" {
+		return p.sequence()
+	}
+
+	s, err := p.scalar(rest, 0)
+	if err != nil {
+		return nil, err
+	}
+	return Rule{s}, nil
+}
+
+// sequence reads a block sequence of `- item` lines following the current line
+func (p *yamlParser) sequence() (Rule, error) {
+	rule := Rule{}
+	indent := -1
+	for p.n+1 < len(p.lines) {
+		line := p.lines[p.n+1]
+		if yamlBlank(line) {
+			p.n++
+			continue
+		}
+		i := yamlIndent(line)
+		// YAML allows sequences at the same indentation as their key
+		if i == 0 && !strings.HasPrefix(line, "-") {
+			break
+		}
+		if indent < 0 {
+			indent = i
+		}
+		if i != indent {
+			p.n++
+			return nil, p.errorf("inconsistent indentation in list")
+		}
+		p.n++
+
+		item := strings.TrimSpace(line[i:])
+		if item != "-" && !strings.HasPrefix(item, "- ") {
+			return nil, p.errorf("expected '- ' list item")
+		}
+		item = strings.TrimSpace(yamlStripComment(item[1:]))
+		s, err := p.scalar(item, indent)
+		if err != nil {
+			return nil, err
+		}
+		rule = append(rule, s)
+	}
+	return rule, nil
+}
+
+// flowSequence reads a single line `[a, "b", 'c']` list, which may be
+// followed by a comment
+func (p *yamlParser) flowSequence(s string) (Rule, error) {
+	s = strings.TrimSpace(s[1:])
+	rule := Rule{}
+	for {
+		if s == "" {
+			return nil, p.errorf("flow lists must be closed on the same line")
+		}
+		// Empty, or after a trailing comma
+		if s[0] == ']' {
+			break
+		}
+
+		var item string
+		if s[0] == '"' || s[0] == '\'' {
+			v, rest, err := p.quoted(s)
+			if err != nil {
+				return nil, err
+			}
+			item, s = v, strings.TrimSpace(rest)
+		} else {
+			end := strings.IndexAny(s, ",]")
+			if end < 0 {
+				end = len(s)
+			}
+			item, s = strings.TrimSpace(s[:end]), s[end:]
+		}
+		rule = append(rule, item)
+
+		if s == "" {
+			return nil, p.errorf("flow lists must be closed on the same line")
+		}
+		if s[0] == ']' {
+			break
+		}
+		if s[0] != ',' {
+			return nil, p.errorf("expected ',' between list items")
+		}
+		s = strings.TrimSpace(s[1:])
+	}
+
+	if strings.TrimSpace(yamlStripComment(s[1:])) != "" {
+		return nil, p.errorf("unexpected text after flow list")
+	}
+	return rule, nil
+}
+
+// scalar reads a value starting on the current line. Blocks use the lines
+// after it which are indented further than parent
+func (p *yamlParser) scalar(s string, parent int) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	switch s[0] {
+	case '"', '\'':
+		v, rest, err := p.quoted(s)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(yamlStripComment(rest)) != "" {
+			return "", p.errorf("unexpected text after quoted value")
+		}
+		return v, nil
+	case '|', '>':
+		return p.block(s, parent)
+	case '[', '{', '&', '*', '!', '%', '@', '`':
+		return "", p.errorf("unsupported value %q, try quoting it", s)
+	}
+	return s, nil
+}
+
+// quoted reads a single or double quoted string from the start of s, returning
+// the value and whatever follows the closing quote
+func (p *yamlParser) quoted(s string) (string, string, error) {
+	if s[0] == '\'' {
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				b.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), s[i+1:], nil
+		}
+		return "", "", p.errorf("unterminated quoted value")
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", p.errorf("invalid quoted value %s", s[:i+1])
+			}
+			return v, s[i+1:], nil
+		}
+	}
+	return "", "", p.errorf("unterminated quoted value")
+}
+
+// block reads a literal (|) or folded (>) block scalar, with an optional
+// chomping indicator (- or +)
+func (p *yamlParser) block(header string, parent int) (string, error) {
+	style, chomp := header[0], byte(0)
+	if len(header) > 1 {
+		chomp = header[1]
+		if (chomp != '-' && chomp != '+') || len(header) > 2 {
+			return "", p.errorf("unsupported block header %q", header)
+		}
+	}
+
+	var lines []string
+	indent := -1
+	for p.n+1 < len(p.lines) {
+		line := p.lines[p.n+1]
+		// Unlike elsewhere a '#' here is part of the text, not a comment
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			p.n++
+			continue
+		}
+		i := yamlIndent(line)
+		if i <= parent {
+			break
+		}
+		if indent < 0 {
+			indent = i
+		}
+		if i < indent {
+			break
+		}
+		lines = append(lines, line[indent:])
+		p.n++
+	}
+
+	// Trailing blank lines belong to the block only as far as chomping is concerned
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var out string
+	if style == '|' {
+		out = strings.Join(lines, "\n")
+	} else {
+		out = yamlFold(lines)
+	}
+
+	switch {
+	case len(lines) == 0:
+	case chomp == '-':
+	case chomp == '+':
+		out += strings.Repeat("\n", trailing+1)
+	default:
+		out += "\n"
+	}
+	return out, nil
+}
+
+// yamlFold joins lines with spaces, keeping breaks for blank and more indented lines
+func yamlFold(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			switch {
+			case prev != "" && line != "" && yamlIndent(prev) == 0 && yamlIndent(line) == 0:
+				b.WriteByte(' ')
+			case prev != "" && line == "" && yamlIndent(prev) == 0:
+				// A paragraph's own line break is folded away by the blank lines after it
+			default:
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func yamlBlank(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || trimmed[0] == '#'
+}
+
+func yamlIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// yamlStripComment removes a trailing ` # comment` from an unquoted value
+func yamlStripComment(s string) string {
+	trimmed := strings.TrimLeft(s, " \t")
+	if trimmed != "" && (trimmed[0] == '"' || trimmed[0] == '\'') {
+		return s
+	}
+	if strings.HasPrefix(trimmed, "#") {
+		return ""
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return s[:i]
+		}
+	}
+	return s
+}
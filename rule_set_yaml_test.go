@@ -0,0 +1,62 @@
+package tracery
+
+import "testing"
+
+func TestParseYAML(t *testing.T) {
+	t.Run("valid inputs", func(t *testing.T) {
+		var tests = []struct {
+			input    string
+			expected RuleSet
+		}{
+			{``, RuleSet{}},
+			{"x: a", RuleSet{"x": Rule{"a"}}},
+			{"---\nx: a\n", RuleSet{"x": Rule{"a"}}},
+			{"x: a, b. c", RuleSet{"x": Rule{"a, b. c"}}},
+			{"x: a # comment", RuleSet{"x": Rule{"a"}}},
+			{"# comment\nx: a", RuleSet{"x": Rule{"a"}}},
+			{`x: "#a# \"b\""`, RuleSet{"x": Rule{`#a# "b"`}}},
+			{`x: '#a# ''b'''`, RuleSet{"x": Rule{`#a# 'b'`}}},
+			{`"x y": a`, RuleSet{"x y": Rule{"a"}}},
+			{"x: [a, '#b#', \"c\"]", RuleSet{"x": Rule{"a", "#b#", "c"}}},
+			{"x: []", RuleSet{"x": Rule{}}},
+			{"x: [\"#a# #b#\", 'c d #e#'] # comment", RuleSet{"x": Rule{"#a# #b#", "c d #e#"}}},
+			{"x: [\"#a# #b#\"]", RuleSet{"x": Rule{"#a# #b#"}}},
+			{"x:\n  - a\n  - '#b#'\ny: c", RuleSet{"x": Rule{"a", "#b#"}, "y": Rule{"c"}}},
+			{"x:\n- a\n- b", RuleSet{"x": Rule{"a", "b"}}},
+			{"x: |\n  #a#\n    b\n\n  c\ny: d", RuleSet{"x": Rule{"#a#\n  b\n\nc\n"}, "y": Rule{"d"}}},
+			{"x: |-\n  a\n  b\n\n", RuleSet{"x": Rule{"a\nb"}}},
+			{"x: |+\n  a\n\n", RuleSet{"x": Rule{"a\n\n"}}},
+			{"x: >\n  a\n  b\n\n  c\n", RuleSet{"x": Rule{"a b\nc\n"}}},
+			{"x:\n  - |\n    a\n    b\n  - c", RuleSet{"x": Rule{"a\nb\n", "c"}}},
+		}
+
+		for _, tt := range tests {
+			set, err := ParseYAML([]byte(tt.input))
+			if err != nil {
+				t.Errorf("ParseYAML(%q): encountered error: %v", tt.input, err)
+			}
+			if ruleSetEqual(set.RuleSet(), tt.expected) == false {
+				t.Errorf("ParseYAML(%q): expected '%q', got '%q'", tt.input, tt.expected, set)
+			}
+		}
+	})
+	t.Run("inputs which should error", func(t *testing.T) {
+		var tests = []string{
+			"x",
+			"  x: a",
+			"x: a\nx: b",
+			`x: "a`,
+			"x: 'a' b",
+			"x: [a, b",
+			"x: [a] b",
+			"x: {a: b}",
+			"x:\n  - a\n    - b",
+		}
+
+		for _, input := range tests {
+			if _, err := ParseYAML([]byte(input)); err == nil {
+				t.Errorf("ParseYAML(%q): expected an error", input)
+			}
+		}
+	})
+}
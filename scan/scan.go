@@ -23,6 +23,8 @@ import (
 type Token struct {
 	Type  Type
 	Value string
+	// Escaped is set on a word with an escaped char, so `\POP` isn't a keyword
+	Escaped bool
 }
 
 // Type of emitted token
@@ -44,6 +46,8 @@ const (
 	Comma        // ,
 	Octo         // #
 	Period       // .
+	LeftBrace    // {
+	RightBrace   // }
 )
 
 // Pretend to be a rune but signal instead
@@ -59,6 +63,13 @@ type Scanner struct {
 	size   int
 	state  stateFunc
 	tokens []Token
+	// read is the number of tokens taken with Next
+	read int
+}
+
+// Mark is a point in the input which the scanner can be Reset to
+type Mark struct {
+	s Scanner
 }
 
 func New(input string) *Scanner {
@@ -82,11 +93,27 @@ func (s *Scanner) Next() Token {
 
 	if len(s.tokens) > 0 {
 		s.tokens = s.tokens[1:]
+		s.read++
 	}
 
 	return token
 }
 
+// Pos is the number of tokens read so far
+func (s *Scanner) Pos() int {
+	return s.read
+}
+
+// Mark records where the scanner is, so it can go back there
+func (s *Scanner) Mark() Mark {
+	return Mark{s: *s}
+}
+
+// Reset goes back to a mark, to read the same tokens again
+func (s *Scanner) Reset(m Mark) {
+	*s = m.s
+}
+
 func (s *Scanner) next() rune {
 	if s.end == len(s.input) {
 		return eof
@@ -102,8 +129,10 @@ func (s *Scanner) consume() {
 
 func (s *Scanner) emit(t Type) {
 	value := s.input[s.start:s.end]
+	escaped := false
 	if t == Word {
 		// BackStroke is only used in words as an escape, so we are cleaning up here
+		escaped = strings.Contains(value, `\`)
 		value = strings.Replace(value, `\`, "", int(-1))
 		// ignore empty values
 		if len(value) == 0 {
@@ -111,7 +140,7 @@ func (s *Scanner) emit(t Type) {
 			return
 		}
 	}
-	s.tokens = append(s.tokens, Token{Type: t, Value: value})
+	s.tokens = append(s.tokens, Token{Type: t, Value: value, Escaped: escaped})
 	s.start = s.end
 }
 
@@ -161,6 +190,13 @@ Loop:
 		case r == '.':
 			nextState = lexChar(Period)
 			break Loop
+		// braces, for CBDQ's {svg ...} and {img ...}
+		case r == '{':
+			nextState = lexChar(LeftBrace)
+			break Loop
+		case r == '}':
+			nextState = lexChar(RightBrace)
+			break Loop
 
 		// Whitespace
 		case unicode.IsSpace(r):
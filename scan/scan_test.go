@@ -21,6 +21,8 @@ func TestScanSingle(t *testing.T) {
 		{",", []Token{Token{Type: Comma, Value: ","}, Token{Type: EOF, Value: ""}}},
 		{"#", []Token{Token{Type: Octo, Value: "#"}, Token{Type: EOF, Value: ""}}},
 		{".", []Token{Token{Type: Period, Value: "."}, Token{Type: EOF, Value: ""}}},
+		{"{", []Token{Token{Type: LeftBrace, Value: "{"}, Token{Type: EOF, Value: ""}}},
+		{"}", []Token{Token{Type: RightBrace, Value: "}"}, Token{Type: EOF, Value: ""}}},
 	}
 
 	for _, tt := range tests {
@@ -40,17 +42,19 @@ func TestScanSingleEscaped(t *testing.T) {
 		input    string
 		expected []Token
 	}{
-		{`\a`, []Token{Token{Type: Word, Value: `a`}, Token{Type: EOF, Value: ""}}},
-		{`\🥝`, []Token{Token{Type: Word, Value: `🥝`}, Token{Type: EOF, Value: ""}}},
-		{`\[`, []Token{Token{Type: Word, Value: `[`}, Token{Type: EOF, Value: ""}}},
-		{`\]`, []Token{Token{Type: Word, Value: `]`}, Token{Type: EOF, Value: ""}}},
-		{`\(`, []Token{Token{Type: Word, Value: `(`}, Token{Type: EOF, Value: ""}}},
-		{`\)`, []Token{Token{Type: Word, Value: `)`}, Token{Type: EOF, Value: ""}}},
+		{`\a`, []Token{Token{Type: Word, Value: `a`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\🥝`, []Token{Token{Type: Word, Value: `🥝`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\[`, []Token{Token{Type: Word, Value: `[`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\]`, []Token{Token{Type: Word, Value: `]`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\(`, []Token{Token{Type: Word, Value: `(`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\)`, []Token{Token{Type: Word, Value: `)`, Escaped: true}, Token{Type: EOF, Value: ""}}},
 		{`\\`, []Token{Token{Type: BackStroke, Value: `\`}, Token{Type: EOF, Value: ""}}},
-		{`\:`, []Token{Token{Type: Word, Value: `:`}, Token{Type: EOF, Value: ""}}},
-		{`\,`, []Token{Token{Type: Word, Value: `,`}, Token{Type: EOF, Value: ""}}},
-		{`\#`, []Token{Token{Type: Word, Value: `#`}, Token{Type: EOF, Value: ""}}},
-		{`\.`, []Token{Token{Type: Word, Value: `.`}, Token{Type: EOF, Value: ""}}},
+		{`\:`, []Token{Token{Type: Word, Value: `:`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\,`, []Token{Token{Type: Word, Value: `,`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\#`, []Token{Token{Type: Word, Value: `#`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\.`, []Token{Token{Type: Word, Value: `.`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\{`, []Token{Token{Type: Word, Value: `{`, Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\}`, []Token{Token{Type: Word, Value: `}`, Escaped: true}, Token{Type: EOF, Value: ""}}},
 	}
 
 	for _, tt := range tests {
@@ -73,8 +77,8 @@ func TestScanText(t *testing.T) {
 		{" a ", []Token{Token{Type: WhiteSpace, Value: " "}, Token{Type: Word, Value: "a"}, Token{Type: WhiteSpace, Value: " "}, Token{Type: EOF, Value: ""}}},
 		{"a b", []Token{Token{Type: Word, Value: "a"}, Token{Type: WhiteSpace, Value: " "}, Token{Type: Word, Value: "b"}, Token{Type: EOF, Value: ""}}},
 		{"a\nb", []Token{Token{Type: Word, Value: "a"}, Token{Type: WhiteSpace, Value: "\n"}, Token{Type: Word, Value: "b"}, Token{Type: EOF, Value: ""}}},
-		{`\[wow\]`, []Token{Token{Type: Word, Value: "[wow]"}, Token{Type: EOF, Value: ""}}},
-		{`\#wee\#`, []Token{Token{Type: Word, Value: "#wee#"}, Token{Type: EOF, Value: ""}}},
+		{`\[wow\]`, []Token{Token{Type: Word, Value: "[wow]", Escaped: true}, Token{Type: EOF, Value: ""}}},
+		{`\#wee\#`, []Token{Token{Type: Word, Value: "#wee#", Escaped: true}, Token{Type: EOF, Value: ""}}},
 	}
 
 	for _, tt := range tests {
@@ -177,3 +181,23 @@ func TestScanComplex(t *testing.T) {
 		}
 	}
 }
+
+func TestScanReset(t *testing.T) {
+	scanner := New("{a,b]")
+	scanner.Next()
+	mark := scanner.Mark()
+	first := []Token{scanner.Next(), scanner.Next(), scanner.Next()}
+	if scanner.Pos() != 4 {
+		t.Errorf("expected pos 4, actual %d", scanner.Pos())
+	}
+
+	scanner.Reset(mark)
+	if scanner.Pos() != 1 {
+		t.Errorf("expected pos 1, actual %d", scanner.Pos())
+	}
+	for _, expected := range first {
+		if actual := scanner.Next(); actual != expected {
+			t.Errorf("expected %v, actual %v", expected, actual)
+		}
+	}
+}
@@ -4,9 +4,9 @@ package scan
 
 import "strconv"
 
-const _Type_name = "EOFErrorWordWhiteSpaceLeftBracketRightBracketLeftParenRightParenBackStrokeColonCommaOctoPeriod"
+const _Type_name = "EOFErrorWordWhiteSpaceLeftBracketRightBracketLeftParenRightParenBackStrokeColonCommaOctoPeriodLeftBraceRightBrace"
 
-var _Type_index = [...]uint8{0, 3, 8, 12, 22, 33, 45, 54, 64, 74, 79, 84, 88, 94}
+var _Type_index = [...]uint8{0, 3, 8, 12, 22, 33, 45, 54, 64, 74, 79, 84, 88, 94, 103, 113}
 
 func (i Type) String() string {
 	if i < 0 || i >= Type(len(_Type_index)-1) {
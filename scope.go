@@ -0,0 +1,247 @@
+package tracery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/martletandco/tracery-go/exec"
+)
+
+// scope is the context a single Flatten runs in. Inline actions change the
+// scope's own stacks, so the Grammar is left as it was unless the changes are
+// committed
+type scope struct {
+	g *Grammar
+	// Symbols changed during this expansion, a cleared symbol has a nil stack
+	value map[string]*stack
+	// Symbols expanded during this expansion
+	symbols map[string]bool
+
+	// tracing keeps track of the derivation path, which is only needed when
+	// recording or replaying a trace, seeding by path, or telling a Chooser
+	tracing bool
+	frames  []frame
+	counts  map[string]int
+	// trace, when set, records each node of the derivation
+	trace *Trace
+	// replay maps derivation paths to the option to pick, apart from those in
+	// the reroll subtree
+	replay map[string]int
+	reroll string
+	// seeded picks options from seed and the derivation path, not Rand
+	seeded bool
+	seed   int64
+	// keyed is set by FlattenSeeded, whose key takes precedence over a Chooser
+	keyed bool
+}
+
+// frame is a node of the derivation being expanded
+type frame struct {
+	path   string
+	option int
+	// node is the index of the node in the trace
+	node int
+	// counts is the number of times each symbol has been expanded inside this
+	// node, so each gets its own path
+	counts map[string]int
+}
+
+func newScope(g *Grammar) *scope {
+	s := &scope{g: g, value: make(map[string]*stack), symbols: make(map[string]bool)}
+	if g.PathSeeded {
+		s.seedPaths(g.Seed)
+	}
+	if g.Chooser != nil {
+		s.tracing = true
+	}
+	return s
+}
+
+// seedPaths picks each option from the seed and its derivation path
+func (s *scope) seedPaths(seed int64) {
+	s.tracing, s.seeded, s.seed = true, true, seed
+}
+
+// rules gives the current stack for a symbol
+func (s *scope) rules(key string) *stack {
+	if rules, ok := s.value[key]; ok {
+		return rules
+	}
+	return s.g.value[key]
+}
+
+// commit writes the changes made in this scope through to the grammar
+func (s *scope) commit() {
+	if len(s.value) == 0 {
+		return
+	}
+	s.g.own()
+	for key, rules := range s.value {
+		if rules == nil {
+			delete(s.g.value, key)
+			continue
+		}
+		s.g.value[key] = rules
+	}
+	s.value = make(map[string]*stack)
+}
+
+// Context implementation below
+
+func (s *scope) Lookup(key string) exec.Operation {
+	return s.rules(key).top()
+}
+func (s *scope) Push(key string, value exec.Operation) {
+	s.value[key] = s.rules(key).push(value)
+}
+func (s *scope) Pop(key string) {
+	rules := s.rules(key)
+	if rules.len() <= 1 {
+		// Nothing left to pop, as with Grammar.Pop
+		return
+	}
+	s.value[key] = rules.next
+}
+func (s *scope) Clear(key string) {
+	s.value[key] = nil
+}
+func (s *scope) Intn(n int) int {
+	return s.g.Intn(n)
+}
+func (s *scope) LookupModifier(key string) (exec.Modifier, bool) {
+	return s.g.LookupModifier(key)
+}
+
+// Choose picks an option, recording it in the current node of the derivation
+func (s *scope) Choose(symbol string, options []exec.Operation) int {
+	i := s.choose(symbol, options)
+	if symbol != "" && len(s.frames) > 0 {
+		f := &s.frames[len(s.frames)-1]
+		f.option = i
+		if s.trace != nil {
+			s.trace.Nodes[f.node].Option = i
+		}
+	}
+	return i
+}
+
+// choose picks the replayed option, the pinned option, the option seeded by
+// a FlattenSeeded key, or lets the grammar's Chooser pick one if it has one
+func (s *scope) choose(symbol string, options []exec.Operation) int {
+	if i, ok := s.replayed(len(options)); ok {
+		return i
+	}
+	if i, ok := s.g.pinned(symbol, options); ok {
+		return i
+	}
+	if s.keyed && len(s.frames) > 0 {
+		return pathIntn(s.seed, s.frames[len(s.frames)-1].path, len(options))
+	}
+	if s.g.Chooser == nil {
+		// A rerolled node would pick the same option again from its path, so
+		// it's left to Rand
+		if s.seeded && len(s.frames) > 0 && !s.rerolling() {
+			return pathIntn(s.seed, s.frames[len(s.frames)-1].path, len(options))
+		}
+		return s.Intn(len(options))
+	}
+
+	c := Choice{Symbol: symbol, Options: make([]string, len(options))}
+	if symbol != "" && len(s.frames) > 0 {
+		c.Path = s.frames[len(s.frames)-1].path
+	}
+	for i, option := range options {
+		c.Options[i] = exec.Source(option)
+	}
+	i := s.g.Chooser.Choose(c)
+	if i < 0 || i >= len(options) {
+		panic(fmt.Sprintf("tracery: chooser picked option %d of %d for %q", i, len(options), symbol))
+	}
+	return i
+}
+
+// replayed gives the option the current node picked when it was traced,
+// unless it's being rerolled or the option no longer exists
+func (s *scope) replayed(n int) (int, bool) {
+	if s.replay == nil || len(s.frames) == 0 || s.rerolling() {
+		return 0, false
+	}
+	i, ok := s.replay[s.frames[len(s.frames)-1].path]
+	return i, ok && i < n
+}
+
+// rerolling reports whether the current node is in the subtree being rerolled
+func (s *scope) rerolling() bool {
+	if s.replay == nil || len(s.frames) == 0 {
+		return false
+	}
+	path := s.frames[len(s.frames)-1].path
+	return path == s.reroll || strings.HasPrefix(path, s.reroll+"/")
+}
+
+// Tracer implementation below
+
+func (s *scope) Enter(symbol string) {
+	if !s.tracing {
+		return
+	}
+
+	prefix := ""
+	if s.counts == nil {
+		s.counts = make(map[string]int)
+	}
+	counts := s.counts
+	if len(s.frames) > 0 {
+		parent := &s.frames[len(s.frames)-1]
+		if parent.counts == nil {
+			parent.counts = make(map[string]int)
+		}
+		counts = parent.counts
+		prefix = parent.path + "/" + strconv.Itoa(parent.option) + "/"
+	}
+
+	// The first animal inside a node is `animal`, the next `animal[1]`
+	path := prefix + symbol
+	if n := counts[symbol]; n > 0 {
+		path += "[" + strconv.Itoa(n) + "]"
+	}
+	counts[symbol]++
+
+	f := frame{path: path}
+	if s.trace != nil {
+		f.node = len(s.trace.Nodes)
+		s.trace.Nodes = append(s.trace.Nodes, TraceNode{Path: path, Symbol: symbol})
+	}
+	s.frames = append(s.frames, f)
+}
+func (s *scope) Leave() {
+	if !s.tracing {
+		return
+	}
+	s.frames = s.frames[:len(s.frames)-1]
+}
+
+// Observer implementation below
+
+func (s *scope) ObserveSymbol(key string) {
+	s.symbols[key] = true
+	if s.g.Coverage != nil {
+		s.g.Coverage.Symbol(key)
+	}
+}
+func (s *scope) ObserveChoice(symbol string, sel exec.Select, i int) {
+	if s.g.Coverage == nil {
+		return
+	}
+	// Only the rules a grammar was made with are reported, and counting any
+	// other Select, e.g. one parsed from the input, would grow without bound
+	if base, ok := s.g.base[symbol].top().(exec.Select); ok && base.Same(sel) {
+		s.g.Coverage.Choice(sel, i)
+	}
+}
+func (s *scope) ObserveModifier(key string) {
+	if s.g.Coverage != nil {
+		s.g.Coverage.Modifier(key)
+	}
+}
@@ -0,0 +1,191 @@
+package tracery
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/martletandco/tracery-go/exec"
+	"github.com/martletandco/tracery-go/parse"
+)
+
+// ErrExhausted is returned by FlattenSearch when every possible output has been
+// tried, so none can meet the constraints
+var ErrExhausted = errors.New("no possible output meets the constraints")
+
+// SearchOptions configures FlattenSearch
+type SearchOptions struct {
+	// Budget is the most expansions tried before giving up, or 0 for no limit
+	Budget int
+	// Prune is given the output so far as it's made, and gives up on every
+	// output starting that way by returning true. It must keep returning true
+	// as more output is added, as PruneLongerThan does
+	Prune func(partial string) bool
+	// MaxDepth is the deepest symbols can be nested in an output before it's
+	// pruned, DefaultMaxDepth if not set. Options are tried first to last, so
+	// without it a recursive rule such as `#x#a` would recurse forever
+	MaxDepth int
+}
+
+// DefaultMaxDepth is the MaxDepth used when SearchOptions doesn't set one
+const DefaultMaxDepth = 100
+
+// PruneLongerThan prunes outputs once they're longer than n runes
+func PruneLongerThan(n int) func(partial string) bool {
+	return func(partial string) bool {
+		return utf8.RuneCountInString(partial) > n
+	}
+}
+
+// FlattenSearch looks for an output passing every constraint by trying each
+// option of every choice in turn, instead of at random as FlattenMeeting does.
+// After a failed output it goes back to the most recent choice with options
+// left, so it finds outputs rejection sampling would rarely hit.
+//
+// Each expansion starts from the grammar's rules, so pushes and pops made down
+// one branch never leak into another. Without a Budget, a grammar with endless
+// outputs may never return. The error is either ErrExhausted or, when the
+// budget runs out, a *ConstraintError where pruned outputs, including those
+// nested deeper than MaxDepth, are counted as "pruned". Outputs deeper than
+// MaxDepth aren't ruled out, so they also give a *ConstraintError rather
+// than ErrExhausted once every other output has been tried
+func (g *Grammar) FlattenSearch(input string, opts SearchOptions, constraints ...Constraint) (string, error) {
+	tree := parse.String(input)
+	failures := make(map[string]int)
+	var choices []choice
+	tooDeep := false
+	maxDepth := opts.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	for attempt := 0; opts.Budget == 0 || attempt < opts.Budget; attempt++ {
+		s := &searchScope{scope: newScope(g), choices: choices, prune: opts.Prune, maxDepth: maxDepth}
+		text, pruned := s.resolve(tree)
+		choices = s.choices
+		tooDeep = tooDeep || s.tooDeep
+
+		if pruned {
+			failures["pruned"]++
+		} else if meets(Output{Text: text, symbols: s.symbols}, constraints, failures) {
+			if g.Persistent {
+				s.commit()
+			}
+			return text, nil
+		}
+
+		if !backtrack(&choices) {
+			if tooDeep {
+				return "", &ConstraintError{Attempts: attempt + 1, Failures: failures}
+			}
+			return "", ErrExhausted
+		}
+	}
+
+	return "", &ConstraintError{Attempts: opts.Budget, Failures: failures}
+}
+
+// choice is the option picked out of n at one Select
+type choice struct {
+	i, n int
+}
+
+// backtrack moves on to the next untried option of the most recent choice
+// with any left, dropping the choices made after it
+func backtrack(choices *[]choice) bool {
+	c := *choices
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].i+1 < c[i].n {
+			c[i].i++
+			*choices = c[:i+1]
+			return true
+		}
+	}
+	return false
+}
+
+// pruned is panicked with to stop an expansion part way through
+type pruned struct{}
+
+// searchScope replays a list of choices, then picks the first option of any
+// choice after them, adding it to the list
+type searchScope struct {
+	*scope
+	choices []choice
+	next    int
+	prune   func(partial string) bool
+	partial strings.Builder
+	held    int
+	// depth is how deeply symbols are nested, pruned past maxDepth
+	depth    int
+	maxDepth int
+	tooDeep  bool
+}
+
+// resolve expands the tree, unless it's pruned part way through
+func (s *searchScope) resolve(tree exec.Operation) (out string, wasPruned bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(pruned); !ok {
+				panic(r)
+			}
+			// Choices after the pruned output weren't made
+			s.choices = s.choices[:s.next]
+			wasPruned = true
+		}
+	}()
+	return tree.Resolve(s), false
+}
+
+func (s *searchScope) Intn(n int) int {
+	if s.next < len(s.choices) {
+		c := s.choices[s.next]
+		s.next++
+		return c.i
+	}
+	s.choices = append(s.choices, choice{i: 0, n: n})
+	s.next++
+	return 0
+}
+
+// Choose ignores the grammar's Chooser, as every option is tried in turn, but
+// keeps to pins
+func (s *searchScope) Choose(symbol string, options []exec.Operation) int {
+	if i, ok := s.g.pinned(symbol, options); ok {
+		return i
+	}
+	return s.Intn(len(options))
+}
+
+// Tracer implementation below
+
+func (s *searchScope) Enter(symbol string) {
+	s.depth++
+	if s.depth > s.maxDepth {
+		s.tooDeep = true
+		panic(pruned{})
+	}
+	s.scope.Enter(symbol)
+}
+func (s *searchScope) Leave() {
+	s.depth--
+	s.scope.Leave()
+}
+
+// Writer implementation below
+
+func (s *searchScope) Write(out string) {
+	if s.held > 0 || s.prune == nil {
+		return
+	}
+	s.partial.WriteString(out)
+	if s.prune(s.partial.String()) {
+		panic(pruned{})
+	}
+}
+func (s *searchScope) Hold() {
+	s.held++
+}
+func (s *searchScope) Release() {
+	s.held--
+}
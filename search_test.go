@@ -0,0 +1,102 @@
+package tracery
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestFlattenSearch(t *testing.T) {
+	exactly := func(out string) Constraint {
+		return Matches(regexp.MustCompile("^" + regexp.QuoteMeta(out) + "$"))
+	}
+
+	t.Run("it tries every option until one passes", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("size", "big", "small", "tiny")
+		g.PushRule("animal", "fox", "dog", "emu")
+		got, err := g.FlattenSearch("#size# #animal#", SearchOptions{}, MaxLength(7), IncludesSymbol("animal"), Matches(regexp.MustCompile("emu")))
+		want := "big emu"
+		if err != nil || got != want {
+			t.Errorf("got '%s' (%v) want '%s'", got, err, want)
+		}
+	})
+	t.Run("it doesn't keep pushes from a failed branch", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("pet", "cat")
+		g.PushRule("origin", "[pet:dog]#pet#", "#pet#")
+		got, err := g.FlattenSearch("#origin#", SearchOptions{}, exactly("cat"))
+		want := "cat"
+		if err != nil || got != want {
+			t.Errorf("got '%s' (%v) want '%s'", got, err, want)
+		}
+	})
+	t.Run("it reports when no output is possible", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("animal", "fox", "dog")
+		_, err := g.FlattenSearch("#animal#", SearchOptions{}, exactly("emu"))
+		if err != ErrExhausted {
+			t.Errorf("got '%v' want '%v'", err, ErrExhausted)
+		}
+	})
+	t.Run("it gives up when the budget runs out", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("animal", "fox", "dog", "emu")
+		_, err := g.FlattenSearch("#animal#", SearchOptions{Budget: 2}, exactly("emu"))
+		var cerr *ConstraintError
+		if !errors.As(err, &cerr) || cerr.Attempts != 2 {
+			t.Errorf("got '%v' want 2 attempts", err)
+		}
+	})
+	t.Run("it prunes outputs which are already too long", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("a", "aaaaa", "aaaaa", "aaaaa", "aaaaa", "a")
+		g.PushRule("b", "bbbbb", "bbbbb", "b")
+		search := SearchOptions{Budget: 7, Prune: PruneLongerThan(2)}
+		got, err := g.FlattenSearch("#a##b#", search, exactly("ab"))
+		want := "ab"
+		if err != nil || got != want {
+			t.Errorf("got '%s' (%v) want '%s'", got, err, want)
+		}
+
+		_, err = g.FlattenSearch("#a##b#", SearchOptions{Budget: 7}, exactly("ab"))
+		if err == nil {
+			t.Errorf("expected the budget to run out without pruning")
+		}
+	})
+	t.Run("it backtracks out of recursive rules", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("x", "#x#a", "b")
+		_, err := g.FlattenSearch("#x#", SearchOptions{Budget: 10, Prune: PruneLongerThan(5)}, exactly("baa"))
+		if err == nil {
+			t.Errorf("expected the budget to run out at the default depth")
+		}
+
+		search := SearchOptions{Budget: 10, MaxDepth: 4, Prune: PruneLongerThan(5)}
+		got, err := g.FlattenSearch("#x#", search, exactly("baa"))
+		want := "baa"
+		if err != nil || got != want {
+			t.Errorf("got '%s' (%v) want '%s'", got, err, want)
+		}
+
+		search = SearchOptions{MaxDepth: 3}
+		_, err = g.FlattenSearch("#x#", search, exactly("baaa"))
+		var cerr *ConstraintError
+		if !errors.As(err, &cerr) || cerr.Failures["pruned"] == 0 {
+			t.Errorf("got '%v' want pruned attempts", err)
+		}
+	})
+	t.Run("it only prunes on text which is output", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("long", "a very long value")
+		g.AddModifyFunc("first", func(value string, params ...string) string {
+			return value[:1]
+		})
+		search := SearchOptions{Prune: PruneLongerThan(2)}
+		got, err := g.FlattenSearch("[x:#long#]#long.first#!", search, MaxLength(2))
+		want := "a!"
+		if err != nil || got != want {
+			t.Errorf("got '%s' (%v) want '%s'", got, err, want)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package tracery
+
+import (
+	"hash/fnv"
+	"math/bits"
+
+	"github.com/martletandco/tracery-go/parse"
+)
+
+// FlattenSeeded flattens the input with options picked from the key, e.g. a
+// user ID, so the same key always gives the same output. The output only
+// depends on the key and the grammar, not on Rand, Seed, any Chooser or the
+// Go version, and changes to one part of the grammar leave the rest of the
+// output as it was, as with PathSeeded. Pins still pick their options
+func (g *Grammar) FlattenSeeded(input string, key string) string {
+	s := newScope(g)
+	s.seedPaths(keySeed(key))
+	s.keyed = true
+	out := parse.String(input).Resolve(s)
+	if g.Persistent {
+		s.commit()
+	}
+	return out
+}
+
+// keySeed hashes a key into a seed with 64 bit FNV-1a, which is specified and
+// so won't change
+func keySeed(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// pathIntn picks a number in [0,n) from the seed and a derivation path, always
+// the same number for the same seed and path
+func pathIntn(seed int64, path string, n int) int {
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	x := splitmix64(uint64(seed) ^ h.Sum64())
+	// Scale to [0,n) by the high bits of x*n, which avoids the bias of x%n
+	hi, _ := bits.Mul64(x, uint64(n))
+	return int(hi)
+}
+
+// splitmix64 scrambles x so similar inputs give unrelated outputs
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,117 @@
+package tracery
+
+import "testing"
+
+func TestPathSeeded(t *testing.T) {
+	seeded := func(seed int64) Grammar {
+		g := NewGrammar()
+		g.PathSeeded, g.Seed = true, seed
+		g.PushRule("origin", "#size# #animal#, #size# #animal#")
+		g.PushRule("size", "big", "small", "tiny", "huge", "wee", "vast")
+		g.PushRule("animal", "fox", "dog", "emu", "cow", "owl", "yak")
+		return g
+	}
+
+	t.Run("it gives the same output for the same seed", func(t *testing.T) {
+		g := seeded(1)
+		first := g.Flatten("#origin#")
+		for i := 0; i < 5; i++ {
+			again := seeded(1)
+			if got := again.Flatten("#origin#"); got != first {
+				t.Errorf("got '%s' want '%s'", got, first)
+			}
+		}
+	})
+	t.Run("it gives different outputs for different seeds", func(t *testing.T) {
+		outs := map[string]bool{}
+		for seed := int64(0); seed < 10; seed++ {
+			g := seeded(seed)
+			outs[g.Flatten("#origin#")] = true
+		}
+		if len(outs) < 5 {
+			t.Errorf("got only %d different outputs from 10 seeds", len(outs))
+		}
+	})
+	t.Run("it leaves unrelated choices alone after an edit", func(t *testing.T) {
+		for seed := int64(0); seed < 20; seed++ {
+			g := seeded(seed)
+			before := g.FlattenTrace("#origin#")
+
+			edited := seeded(seed)
+			edited.PushRule("size", "big", "small", "tiny", "huge", "wee", "vast", "teeny", "giant")
+			after := edited.FlattenTrace("#origin#")
+
+			for i, node := range before.Nodes {
+				if node.Symbol == "animal" && after.Nodes[i].Option != node.Option {
+					t.Errorf("seed %d: %s picked %d then %d", seed, node.Path, node.Option, after.Nodes[i].Option)
+				}
+			}
+		}
+	})
+	t.Run("it gives each choice its own number", func(t *testing.T) {
+		got := pathIntn(1, "origin/0/animal", 1000) == pathIntn(1, "origin/0/animal[1]", 1000)
+		if got {
+			t.Errorf("expected different paths to give different numbers")
+		}
+	})
+}
+
+func TestFlattenSeeded(t *testing.T) {
+	seededGrammar := func() Grammar {
+		g := NewGrammar()
+		g.PushRule("origin", "A #size# #colour# #animal#")
+		g.PushRule("size", "big", "small", "tiny", "huge", "wee", "vast")
+		g.PushRule("colour", "red", "green", "blue", "grey", "gold", "pink")
+		g.PushRule("animal", "fox", "dog", "emu", "cow", "owl", "yak")
+		return g
+	}
+
+	t.Run("it gives the same output for the same key", func(t *testing.T) {
+		g := seededGrammar()
+		first := g.FlattenSeeded("#origin#", "user-1")
+		for i := 0; i < 5; i++ {
+			if got := g.FlattenSeeded("#origin#", "user-1"); got != first {
+				t.Errorf("got '%s' want '%s'", got, first)
+			}
+		}
+	})
+	t.Run("it keeps giving the same outputs across releases", func(t *testing.T) {
+		// These must never change, as callers store and rely on them
+		var tests = []struct {
+			key      string
+			expected string
+		}{
+			{"", "A big blue fox"},
+			{"user-1", "A tiny grey dog"},
+			{"user-2", "A vast pink emu"},
+			{"SKU-0042", "A tiny pink cow"},
+			{"🦊", "A big blue emu"},
+		}
+		for _, tt := range tests {
+			g := seededGrammar()
+			g.Rand = func(n int) int { return 0 }
+			g.Seed = 99
+			g.Chooser = FirstChooser{}
+			if got := g.FlattenSeeded("#origin#", tt.key); got != tt.expected {
+				t.Errorf("FlattenSeeded(%v): got '%s' want '%s'", tt.key, got, tt.expected)
+			}
+		}
+	})
+	t.Run("it picks from the key over a Chooser, but not a pin", func(t *testing.T) {
+		g := seededGrammar()
+		g.Chooser = FirstChooser{}
+		g.Pin("size", "wee")
+		if got, want := g.FlattenSeeded("#origin#", "user-1"), "A wee grey dog"; got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+	t.Run("it is stable for hashing", func(t *testing.T) {
+		// FNV-1a test vectors
+		if got, want := uint64(keySeed("")), uint64(0xcbf29ce484222325); got != want {
+			t.Errorf("got %x want %x", got, want)
+		}
+		if got, want := uint64(keySeed("a")), uint64(0xaf63dc4c8601ec8c); got != want {
+			t.Errorf("got %x want %x", got, want)
+		}
+	})
+}
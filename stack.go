@@ -0,0 +1,30 @@
+package tracery
+
+import "github.com/martletandco/tracery-go/exec"
+
+// stack is an immutable list of the rules pushed to a symbol, most recent
+// first. Pushing and popping give a new stack rather than changing the old
+// one, so stacks can be shared between grammars and scopes without copying
+type stack struct {
+	op    exec.Operation
+	next  *stack
+	depth int
+}
+
+func (s *stack) push(op exec.Operation) *stack {
+	return &stack{op: op, next: s, depth: s.len() + 1}
+}
+
+func (s *stack) top() exec.Operation {
+	if s == nil {
+		return nil
+	}
+	return s.op
+}
+
+func (s *stack) len() int {
+	if s == nil {
+		return 0
+	}
+	return s.depth
+}
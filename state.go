@@ -0,0 +1,129 @@
+package tracery
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/martletandco/tracery-go/exec"
+	"github.com/martletandco/tracery-go/parse"
+)
+
+// StateVersion is the version of the format written by MarshalState
+const StateVersion = 1
+
+// state is the format written by MarshalState
+type state struct {
+	Version int `json:"version"`
+	// Symbols are the rules of each symbol, bottom of the stack first. Each
+	// push is a list of options
+	Symbols map[string][][]string `json:"symbols"`
+	Pins    map[string]string     `json:"pins,omitempty"`
+	RNG     *rngState             `json:"rng,omitempty"`
+}
+
+type rngState struct {
+	Type  string `json:"type"`
+	State []byte `json:"state"`
+}
+
+// MarshalState saves what's changed as a grammar is used, so it can carry on
+// where it left off after a restart: the rules of every symbol, including those
+// pushed and popped by a Persistent grammar, any pins, and the position of
+// the RNG if it's a PCG. The grammar must have a PCG or CryptoRNG set with
+// SetRNG, as Rand, like the math/rand NewGrammar uses, can't be saved and the
+// grammar wouldn't carry on where it left off. The rules and modifiers the
+// grammar was made with aren't saved, so restore the state to the same grammar
+// with UnmarshalState.
+// A History keeps its own file so isn't part of the state
+func (g *Grammar) MarshalState() ([]byte, error) {
+	st := state{Version: StateVersion, Symbols: make(map[string][][]string, len(g.value)), Pins: g.pins}
+	for key, rules := range g.value {
+		st.Symbols[key] = stackSources(rules)
+	}
+
+	switch rng := g.rng.(type) {
+	case nil:
+		return nil, fmt.Errorf("can't save the state of Rand, use SetRNG with a PCG or CryptoRNG")
+	case *PCG:
+		b, err := rng.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		st.RNG = &rngState{Type: "pcg", State: b}
+	case CryptoRNG:
+		// Has no state
+	default:
+		return nil, fmt.Errorf("can't save the state of a %T", rng)
+	}
+
+	return json.Marshal(st)
+}
+
+// UnmarshalState restores the state saved by MarshalState, replacing the rules
+// of every symbol and any pins. A saved PCG becomes the grammar's RNG
+func (g *Grammar) UnmarshalState(data []byte) error {
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	if st.Version != StateVersion {
+		return fmt.Errorf("unknown state version %d", st.Version)
+	}
+
+	var rng *PCG
+	if st.RNG != nil {
+		if st.RNG.Type != "pcg" {
+			return fmt.Errorf("unknown RNG %q in state", st.RNG.Type)
+		}
+		rng = &PCG{}
+		if err := rng.UnmarshalBinary(st.RNG.State); err != nil {
+			return err
+		}
+	}
+
+	g.own()
+	g.value = make(map[string]*stack, len(st.Symbols))
+	for key, pushes := range st.Symbols {
+		// Rules the grammar was made with are shared, not parsed again
+		if base := g.base[key]; reflect.DeepEqual(stackSources(base), pushes) {
+			g.value[key] = base
+			continue
+		}
+		var rules *stack
+		for _, options := range pushes {
+			rules = rules.push(parse.Strings(options))
+		}
+		g.value[key] = rules
+	}
+	g.pins = make(map[string]string, len(st.Pins))
+	for key, option := range st.Pins {
+		g.pins[key] = option
+	}
+	if rng != nil {
+		g.SetRNG(rng)
+	}
+	return nil
+}
+
+// stackSources gives the options of each push to a stack, bottom first
+func stackSources(rules *stack) [][]string {
+	pushes := make([][]string, rules.len())
+	for i := len(pushes) - 1; rules != nil; i, rules = i-1, rules.next {
+		pushes[i] = sources(rules.op)
+	}
+	return pushes
+}
+
+// sources gives the source of each option of an operation
+func sources(op exec.Operation) []string {
+	sel, ok := op.(exec.Select)
+	if !ok {
+		return []string{exec.Source(op)}
+	}
+	var out []string
+	for _, option := range sel.Options() {
+		out = append(out, exec.Source(option))
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package tracery
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalState(t *testing.T) {
+	stateGrammar := func() Grammar {
+		g := NewGrammar()
+		g.Persistent = true
+		g.SetRNG(NewPCG(1, 0))
+		g.PushRule("name", "Ada", "Bo", "Cy")
+		g.PushRule("origin", "#hero# the #trait#")
+		g.PushRule("trait", "bold", "wise")
+		return g
+	}
+
+	t.Run("it carries on where it left off", func(t *testing.T) {
+		g := stateGrammar()
+		g.SetRNG(NewPCG(5, 0))
+		g.Flatten("[hero:#name#][trait:#trait#]")
+		g.Pin("name", "Bo")
+		want := g.Flatten("#origin# [x:#name#,#trait#]#x#")
+
+		g = stateGrammar()
+		g.SetRNG(NewPCG(5, 0))
+		g.Flatten("[hero:#name#][trait:#trait#]")
+		g.Pin("name", "Bo")
+		data, err := g.MarshalState()
+		if err != nil {
+			t.Fatalf("MarshalState: encountered error: %v", err)
+		}
+
+		restored := stateGrammar()
+		if err := restored.UnmarshalState(data); err != nil {
+			t.Fatalf("UnmarshalState: encountered error: %v", err)
+		}
+		if got := restored.Flatten("#origin# [x:#name#,#trait#]#x#"); got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		if got, want := restored.StackDepth("trait"), 2; got != want {
+			t.Errorf("got %d rules for trait want %d", got, want)
+		}
+	})
+	t.Run("it restores cleared and popped symbols", func(t *testing.T) {
+		g := stateGrammar()
+		g.Flatten("[name:CLEAR][trait:kind][trait:POP][extra:\\#not a tag\\#]")
+		data, _ := g.MarshalState()
+
+		restored := stateGrammar()
+		restored.UnmarshalState(data)
+		if restored.HasSymbol("name") {
+			t.Errorf("expected name to stay cleared")
+		}
+		if got, want := restored.Flatten("#extra#"), "#not a tag#"; got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		if got, want := restored.Rules("trait"), []string{"bold", "wise"}; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+		restored.Reset()
+		if !restored.HasSymbol("name") {
+			t.Errorf("expected a reset to bring back name")
+		}
+	})
+	t.Run("it refuses to save an RNG it can't restore", func(t *testing.T) {
+		g := NewGrammar()
+		if _, err := g.MarshalState(); err == nil {
+			t.Errorf("expected an error for math/rand")
+		}
+
+		g.SetRNG(NewPCG(1, 0))
+		g.SetRNG(nil)
+		if _, err := g.MarshalState(); err == nil {
+			t.Errorf("expected an error once the RNG is unset")
+		}
+	})
+	t.Run("it refuses unknown versions", func(t *testing.T) {
+		g := stateGrammar()
+		data, _ := g.MarshalState()
+		var raw map[string]interface{}
+		json.Unmarshal(data, &raw)
+		raw["version"] = StateVersion + 1
+		data, _ = json.Marshal(raw)
+		if err := g.UnmarshalState(data); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package tracery
+
+import (
+	"fmt"
+
+	"github.com/martletandco/tracery-go/parse"
+)
+
+// Trace records how an output was made, so it can be remade with Reroll
+type Trace struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+	// Nodes are the symbols expanded, and values pushed by actions, in the
+	// order they were started
+	Nodes []TraceNode `json:"nodes"`
+}
+
+// TraceNode is a symbol expanded as part of an output, and the option it picked
+type TraceNode struct {
+	// Path is where the node is in the derivation, made up of each symbol
+	// above it and the option it picked, e.g. `origin/0/animal` is the first
+	// animal expanded by the first option of origin. Further animals expanded
+	// by the same option are `origin/0/animal[1]` and so on
+	Path   string `json:"path"`
+	Symbol string `json:"symbol"`
+	// Option is the index of the option picked, which is always 0 for a
+	// symbol with only one option
+	Option int `json:"option"`
+}
+
+// FlattenTrace flattens the input as Flatten does, also recording the trace
+func (g *Grammar) FlattenTrace(input string) Trace {
+	t := Trace{Input: input}
+	s := newScope(g)
+	s.tracing, s.trace = true, &t
+	t.Output = parse.String(input).Resolve(s)
+	if g.Persistent {
+		s.commit()
+	}
+	return t
+}
+
+// Reroll remakes a traced output, picking options afresh for the node at the
+// path and every node inside it, e.g. to reroll just one adjective. Every
+// other node picks the option it picked before, so long as it still has it.
+// Everything is expanded again, so anything pushed by the rerolled nodes is
+// seen by the rest of the output. The rerolled nodes pick with Rand, even in
+// a PathSeeded grammar, as their paths would give the same options again.
+//
+// Reroll starts from the grammar's current rules, so it isn't meant for a
+// Persistent grammar, and doesn't keep the actions of the new output
+func (g *Grammar) Reroll(trace Trace, path string) (Trace, error) {
+	replay := make(map[string]int, len(trace.Nodes))
+	found := false
+	for _, node := range trace.Nodes {
+		replay[node.Path] = node.Option
+		found = found || node.Path == path
+	}
+	if !found {
+		return Trace{}, fmt.Errorf("no node at %q in the trace", path)
+	}
+
+	t := Trace{Input: trace.Input}
+	s := newScope(g)
+	s.tracing, s.trace, s.replay, s.reroll = true, &t, replay, path
+	t.Output = parse.String(trace.Input).Resolve(s)
+	return t, nil
+}
@@ -0,0 +1,104 @@
+package tracery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlattenTrace(t *testing.T) {
+	g := NewGrammar()
+	g.PushRule("origin", "#sentence# #sentence#")
+	g.PushRule("sentence", "The #adj# #animal#.")
+	g.PushRule("adj", "quick", "lazy", "red")
+	g.PushRule("animal", "fox", "dog")
+	g.Rand = func(n int) int { return 1 }
+
+	trace := g.FlattenTrace("#origin#")
+	if got, want := trace.Output, "The lazy dog. The lazy dog."; got != want {
+		t.Errorf("got '%s' want '%s'", got, want)
+	}
+	want := []TraceNode{
+		{"origin", "origin", 0},
+		{"origin/0/sentence", "sentence", 0},
+		{"origin/0/sentence/0/adj", "adj", 1},
+		{"origin/0/sentence/0/animal", "animal", 1},
+		{"origin/0/sentence[1]", "sentence", 0},
+		{"origin/0/sentence[1]/0/adj", "adj", 1},
+		{"origin/0/sentence[1]/0/animal", "animal", 1},
+	}
+	if !reflect.DeepEqual(trace.Nodes, want) {
+		t.Errorf("got '%v' want '%v'", trace.Nodes, want)
+	}
+}
+
+func TestReroll(t *testing.T) {
+	t.Run("it only rerolls the node at the path", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("sentence", "The #adj# #animal#.")
+		g.PushRule("adj", "quick", "lazy", "red")
+		g.PushRule("animal", "fox", "dog")
+		g.Rand = func(n int) int { return 1 }
+		trace := g.FlattenTrace("#sentence# #sentence#")
+
+		g.Rand = func(n int) int { return n - 1 }
+		got, err := g.Reroll(trace, "sentence[1]/0/adj")
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		if want := "The lazy dog. The red dog."; got.Output != want {
+			t.Errorf("got '%s' want '%s'", got.Output, want)
+		}
+
+		got, _ = g.Reroll(got, "sentence")
+		if want := "The red dog. The red dog."; got.Output != want {
+			t.Errorf("got '%s' want '%s'", got.Output, want)
+		}
+	})
+	t.Run("it keeps pushes consistent", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("origin", "[hero:#name#]#hero# meets #friend#")
+		g.PushRule("friend", "#name#, #hero#'s friend")
+		g.PushRule("name", "Ada", "Bo", "Cy")
+		g.Rand = func(n int) int { return 0 }
+		trace := g.FlattenTrace("#origin#")
+		if want := "Ada meets Ada, Ada's friend"; trace.Output != want {
+			t.Errorf("got '%s' want '%s'", trace.Output, want)
+		}
+
+		g.Rand = func(n int) int { return 2 }
+		got, _ := g.Reroll(trace, "origin/0/hero/0/name")
+		if want := "Cy meets Ada, Cy's friend"; got.Output != want {
+			t.Errorf("got '%s' want '%s'", got.Output, want)
+		}
+	})
+	t.Run("it rerolls a path seeded grammar", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("origin", "#animal# and #animal#")
+		g.PushRule("animal", "fox", "dog", "emu", "cow", "owl", "yak")
+		g.PathSeeded, g.Seed = true, 7
+		trace := g.FlattenTrace("#origin#")
+
+		g.SetRNG(NewPCG(1, 0))
+		changed := 0
+		for i := 0; i < 20; i++ {
+			got, _ := g.Reroll(trace, "origin/0/animal")
+			if got.Nodes[2].Option != trace.Nodes[2].Option {
+				t.Errorf("got option %d want %d for the node not rerolled", got.Nodes[2].Option, trace.Nodes[2].Option)
+			}
+			if got.Output != trace.Output {
+				changed++
+			}
+		}
+		if changed == 0 {
+			t.Errorf("expected rerolls to change the output")
+		}
+	})
+	t.Run("it refuses paths not in the trace", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("animal", "fox", "dog")
+		trace := g.FlattenTrace("#animal#")
+		if _, err := g.Reroll(trace, "animal/0/size"); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
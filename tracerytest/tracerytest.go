@@ -0,0 +1,196 @@
+// Package tracerytest runs golden tests against a grammar: each case is an
+// expression flattened with a seeded PCG, and the output it should give
+package tracerytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/martletandco/tracery-go"
+)
+
+// TestsKey is the key of the tests section a JSON grammar can have, which
+// loading the grammar ignores
+const TestsKey = "$tests"
+
+// Case is a single golden test
+type Case struct {
+	Expr     string `json:"expr"`
+	Seed     int64  `json:"seed"`
+	Expected string `json:"expected"`
+}
+
+// Result is a case along with the output it actually gave
+type Result struct {
+	Case
+	Actual string
+}
+
+// Passed reports whether the case gave the expected output
+func (r Result) Passed() bool {
+	return r.Actual == r.Expected
+}
+
+// Diff describes a failed case, or is empty if it passed
+func (r Result) Diff() string {
+	if r.Passed() {
+		return ""
+	}
+	return fmt.Sprintf("%s (seed %d)\n- %s\n+ %s\n", r.Expr, r.Seed, r.Expected, r.Actual)
+}
+
+// Run flattens each case with a clone of the grammar seeded with the case's
+// seed, so cases don't affect each other or the grammar. Cases are seeded with
+// a PCG, so their expected output holds across Go versions
+func Run(g *tracery.Grammar, cases []Case) []Result {
+	results := make([]Result, 0, len(cases))
+	for _, c := range cases {
+		clone := g.Clone()
+		clone.SetRNG(tracery.NewPCG(uint64(c.Seed), 0))
+		results = append(results, Result{Case: c, Actual: clone.Flatten(c.Expr)})
+	}
+	return results
+}
+
+// Test runs the cases as part of a Go test, reporting each failure with its diff
+func Test(t testing.TB, g *tracery.Grammar, cases []Case) {
+	t.Helper()
+	for _, r := range Run(g, cases) {
+		if !r.Passed() {
+			t.Errorf("%s", r.Diff())
+		}
+	}
+}
+
+// ParseCases reads a JSON list of cases, or the tests section of a JSON grammar
+func ParseCases(data []byte) ([]Case, error) {
+	var cases []Case
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(data, &cases)
+		return cases, err
+	}
+
+	var grammar map[string]json.RawMessage
+	if err := json.Unmarshal(data, &grammar); err != nil {
+		return nil, err
+	}
+	section, ok := grammar[TestsKey]
+	if !ok {
+		return nil, nil
+	}
+	if err := json.Unmarshal(section, &cases); err != nil {
+		return nil, fmt.Errorf("%s: %v", TestsKey, err)
+	}
+	return cases, nil
+}
+
+// Update rewrites the data ParseCases read so each case expects the output it
+// actually gave. A grammar keeps its symbols in order, but is reformatted
+func Update(data []byte, results []Result) ([]byte, error) {
+	cases := make([]Case, 0, len(results))
+	for _, r := range results {
+		c := r.Case
+		c.Expected = r.Actual
+		cases = append(cases, c)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return marshal(cases)
+	}
+
+	entries, err := objectEntries(data)
+	if err != nil {
+		return nil, err
+	}
+	section, err := json.Marshal(cases)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	found := false
+	for i, entry := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		value := entry.value
+		if entry.key == TestsKey {
+			value, found = section, true
+		}
+		key, _ := json.Marshal(entry.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	if !found {
+		if len(entries) > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + TestsKey + `":`)
+		buf.Write(section)
+	}
+	buf.WriteByte('}')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+func marshal(cases []Case) ([]byte, error) {
+	out, err := json.MarshalIndent(cases, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+type entry struct {
+	key   string
+	value json.RawMessage
+}
+
+// objectEntries reads the keys and values of a JSON object in order
+func objectEntries(data []byte) ([]entry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a grammar object, found %v", tok)
+	}
+
+	var entries []entry
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: tok.(string), value: value})
+	}
+	return entries, nil
+}
+
+// Summary describes how many of the results passed
+func Summary(results []Result) string {
+	failed := 0
+	for _, r := range results {
+		if !r.Passed() {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return fmt.Sprintf("ok %d cases", len(results))
+	}
+	return fmt.Sprintf("FAIL %d of %d cases", failed, len(results))
+}
@@ -0,0 +1,98 @@
+package tracerytest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/martletandco/tracery-go"
+)
+
+func testGrammar() tracery.Grammar {
+	g := tracery.NewGrammar()
+	g.PushRule("animal", "fox", "dog", "emu", "cow")
+	return g
+}
+
+func TestRun(t *testing.T) {
+	t.Run("it gives the same output for the same seed", func(t *testing.T) {
+		g := testGrammar()
+		cases := []Case{{Expr: "#animal# #animal#", Seed: 7}, {Expr: "#animal# #animal#", Seed: 7}}
+		results := Run(&g, cases)
+		if results[0].Actual != results[1].Actual {
+			t.Errorf("got '%s' then '%s'", results[0].Actual, results[1].Actual)
+		}
+	})
+	t.Run("it describes failures", func(t *testing.T) {
+		g := testGrammar()
+		r := Run(&g, []Case{{Expr: "a #missing#", Seed: 1, Expected: "a b"}})[0]
+		got := r.Diff()
+		want := "a #missing# (seed 1)\n- a b\n+ a ((missing))\n"
+		if r.Passed() || got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+		if got := Summary([]Result{r}); got != "FAIL 1 of 1 cases" {
+			t.Errorf("got '%s'", got)
+		}
+	})
+}
+
+func TestParseCases(t *testing.T) {
+	want := []Case{{Expr: "#a#", Seed: 2, Expected: "b"}}
+	var tests = []string{
+		`[{"expr": "#a#", "seed": 2, "expected": "b"}]`,
+		`{"a": "b", "$tests": [{"expr": "#a#", "seed": 2, "expected": "b"}]}`,
+	}
+	for _, input := range tests {
+		got, err := ParseCases([]byte(input))
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseCases(%v): got '%v' (%v) want '%v'", input, got, err, want)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("it rewrites a list of cases", func(t *testing.T) {
+		data := []byte(`[{"expr": "#a#", "seed": 2, "expected": "x"}]`)
+		results := []Result{{Case: Case{Expr: "#a#", Seed: 2, Expected: "x"}, Actual: "b"}}
+		out, err := Update(data, results)
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		got, _ := ParseCases(out)
+		if want := []Case{{Expr: "#a#", Seed: 2, Expected: "b"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+	})
+	t.Run("it rewrites the tests of a grammar, keeping its symbols in order", func(t *testing.T) {
+		data := []byte(`{"z": "b", "a": ["c"], "$tests": [{"expr": "#z#"}]}`)
+		results := []Result{{Case: Case{Expr: "#z#"}, Actual: "b"}}
+		out, err := Update(data, results)
+		if err != nil {
+			t.Fatalf("encountered error: %v", err)
+		}
+		got := string(out)
+		want := `{
+  "z": "b",
+  "a": [
+    "c"
+  ],
+  "$tests": [
+    {
+      "expr": "#z#",
+      "seed": 0,
+      "expected": "b"
+    }
+  ]
+}
+`
+		if got != want {
+			t.Errorf("got '%s' want '%s'", got, want)
+		}
+	})
+}
+
+func TestTest(t *testing.T) {
+	g := testGrammar()
+	r := Run(&g, []Case{{Expr: "#animal#", Seed: 3}})[0]
+	Test(t, &g, []Case{{Expr: "#animal#", Seed: 3, Expected: r.Actual}})
+}
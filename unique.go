@@ -0,0 +1,103 @@
+package tracery
+
+import (
+	"math/rand"
+
+	"github.com/martletandco/tracery-go/parse"
+)
+
+// UniqueOptions configures GenerateUniqueWith
+type UniqueOptions struct {
+	// Workers is the number of goroutines generating outputs, each with a
+	// clone of the grammar. With none the grammar generates them itself
+	Workers int
+	// Seed seeds every worker's PCG, so the same seed and number of workers
+	// always gives the same outputs, in the same order
+	Seed int64
+	// MaxMisses is the number of duplicate outputs in a row taken to mean the
+	// grammar has no new outputs left, 1000 if not set
+	MaxMisses int
+}
+
+// GenerateUnique flattens the input until it has n different outputs. Fewer are
+// returned when the grammar runs out of new outputs, i.e. it keeps repeating
+// outputs it's already given
+func (g *Grammar) GenerateUnique(input string, n int) []string {
+	return g.GenerateUniqueWith(input, n, UniqueOptions{})
+}
+
+// GenerateUniqueWith is GenerateUnique with options, e.g. to generate in
+// parallel. Modifiers, and any Chooser, must be safe to use from more than one
+// goroutine when there are Workers
+func (g *Grammar) GenerateUniqueWith(input string, n int, opts UniqueOptions) []string {
+	maxMisses := opts.MaxMisses
+	if maxMisses == 0 {
+		maxMisses = 1000
+	}
+
+	next, stop := g.generator(input, opts)
+	defer stop()
+
+	seen := make(map[string]bool, n)
+	outs := make([]string, 0, n)
+	for misses := 0; len(outs) < n && misses < maxMisses; {
+		out := next()
+		if seen[out] {
+			misses++
+			continue
+		}
+		seen[out] = true
+		outs = append(outs, out)
+		misses = 0
+	}
+	return outs
+}
+
+// generator gives a func making each output in turn, and one to stop any
+// workers once done
+func (g *Grammar) generator(input string, opts UniqueOptions) (func() string, func()) {
+	tree := parse.String(input)
+	if opts.Workers <= 0 {
+		return func() string {
+			out, s := g.flatten(tree)
+			if g.Persistent {
+				s.commit()
+			}
+			return out
+		}, func() {}
+	}
+
+	seeds := rand.New(rand.NewSource(opts.Seed))
+	done := make(chan struct{})
+	outs := make([]chan string, opts.Workers)
+	for i := range outs {
+		w := g.Clone()
+		w.SetRNG(NewPCG(uint64(seeds.Int63()), 0))
+		outs[i] = make(chan string)
+
+		go func(w *Grammar, out chan<- string) {
+			for {
+				text, s := w.flatten(tree)
+				if w.Persistent {
+					s.commit()
+				}
+				select {
+				case out <- text:
+				case <-done:
+					return
+				}
+			}
+		}(&w, outs[i])
+	}
+
+	// Outputs are taken from each worker in turn so the order doesn't depend
+	// on which worker is quickest
+	turn := 0
+	return func() string {
+			out := <-outs[turn]
+			turn = (turn + 1) % len(outs)
+			return out
+		}, func() {
+			close(done)
+		}
+}
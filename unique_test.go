@@ -0,0 +1,52 @@
+package tracery
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGenerateUnique(t *testing.T) {
+	t.Run("it gives n different outputs", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("size", "big", "small", "tiny")
+		g.PushRule("animal", "fox", "dog", "emu")
+		outs := g.GenerateUnique("#size# #animal#", 5)
+		seen := map[string]bool{}
+		for _, out := range outs {
+			seen[out] = true
+		}
+		if len(outs) != 5 || len(seen) != 5 {
+			t.Errorf("got '%v' want 5 different outputs", outs)
+		}
+	})
+	t.Run("it stops when there are no new outputs", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("animal", "fox", "dog", "emu")
+		got := g.GenerateUnique("#animal#", 10)
+		sort.Strings(got)
+		want := []string{"dog", "emu", "fox"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got '%v' want '%v'", got, want)
+		}
+	})
+	t.Run("it gives the same outputs from the same seed", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("size", "big", "small", "tiny", "huge", "wee")
+		g.PushRule("animal", "fox", "dog", "emu", "cow", "owl")
+		opts := UniqueOptions{Workers: 4, Seed: 42}
+		first := g.GenerateUniqueWith("#size# #animal#", 20, opts)
+		second := g.GenerateUniqueWith("#size# #animal#", 20, opts)
+		if len(first) != 20 || !reflect.DeepEqual(first, second) {
+			t.Errorf("got '%v' then '%v'", first, second)
+		}
+	})
+	t.Run("it stops parallel workers when there are no new outputs", func(t *testing.T) {
+		g := NewGrammar()
+		g.PushRule("animal", "fox", "dog")
+		got := g.GenerateUniqueWith("#animal#", 3, UniqueOptions{Workers: 2, MaxMisses: 50})
+		if len(got) != 2 {
+			t.Errorf("got '%v' want 2 outputs", got)
+		}
+	})
+}